@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"github.com/binaryty/enricher-service/internal/fakeapi"
+	"github.com/binaryty/enricher-service/internal/services/enricher"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// fake-providers serves agify, genderize and nationalize APIs from the dataset file.
+// Point api.age, api.gender and api.nationality of config to its /agify/, /genderize/
+// and /nationalize/ URLs to run the service offline.
+func main() {
+	var (
+		addr        = flag.String("addr", ":8081", "address to listen on")
+		dataset     = flag.String("dataset", "./data/names.csv", "fixture file of names")
+		latency     = flag.Duration("latency", 0, "delay of every response")
+		jitter      = flag.Duration("jitter", 0, "max random delay added to latency")
+		errorRate   = flag.Float64("error-rate", 0, "share of requests failed with 500")
+		tooManyRate = flag.Float64("too-many-rate", 0, "share of requests refused with 429")
+		limit       = flag.Int("limit", 0, "names served per window before 429, no limit if 0")
+		window      = flag.Duration("window", 24*time.Hour, "window of the limit")
+		seed        = flag.Int64("seed", 1, "seed of random delays and failures")
+	)
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	// names are only case-folded as public APIs do.
+	ds, err := enricher.LoadDataset(*dataset, enricher.NewNormalizer(false, nil))
+	if err != nil {
+		logger.Error("can't load dataset", slog.String("[ERROR]", err.Error()))
+		os.Exit(1)
+	}
+
+	handler := fakeapi.NewHandler(ds, fakeapi.Options{
+		Latency:     *latency,
+		Jitter:      *jitter,
+		ErrorRate:   *errorRate,
+		TooManyRate: *tooManyRate,
+		Limit:       *limit,
+		Window:      *window,
+		Seed:        *seed,
+	})
+
+	logger.Info("fake providers started", slog.String("address", *addr))
+
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		logger.Error("server stopped", slog.String("[ERROR]", err.Error()))
+		os.Exit(1)
+	}
+}
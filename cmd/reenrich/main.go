@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"github.com/binaryty/enricher-service/internal/config"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/services/enricher"
+	services "github.com/binaryty/enricher-service/internal/services/people"
+	storage "github.com/binaryty/enricher-service/internal/storage/people/postgres"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// reenrich re-enriches persons matching the flags and prints the report of the run.
+// An interrupted run is stopped and can be continued by -resume.
+func main() {
+	var (
+		olderThan      = flag.Duration("older-than", 0, "re-enrich persons enriched more than this ago")
+		provider       = flag.String("provider", "", "re-enrich persons with values of this provider")
+		maxProbability = flag.Float64("max-probability", 0, "re-enrich persons with gender or nationality probability below this")
+		rate           = flag.Float64("rate", 0, "persons per second, defaults to config")
+		resume         = flag.Int64("resume", 0, "ID of stopped or failed run to continue")
+	)
+
+	cfg := config.MustLoad()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	repo, err := storage.New(cfg)
+	if err != nil {
+		logger.Error("can't initialize storage", slog.String("[ERROR]", err.Error()))
+		os.Exit(1)
+	}
+
+	enricherSrv, err := enricher.New(cfg, logger)
+	if err != nil {
+		logger.Error("can't initialize enricher", slog.String("[ERROR]", err.Error()))
+		os.Exit(1)
+	}
+
+	service := services.New(logger, repo, enricherSrv, services.WithBulkConcurrency(cfg.Enricher.BulkWorkers))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *rate < 0 {
+		logger.Error("invalid rate", slog.Float64("rate", *rate))
+		os.Exit(1)
+	}
+
+	if *maxProbability < 0 || *maxProbability > 1 {
+		logger.Error("invalid max probability, it must be between 0 and 1", slog.Float64("max-probability", *maxProbability))
+		os.Exit(1)
+	}
+
+	var run *models.ReenrichRun
+	if *resume != 0 {
+		run, err = repo.SelectReenrichRun(ctx, *resume)
+	} else {
+		params := models.ReenrichParams{
+			Provider:       *provider,
+			MaxProbability: *maxProbability,
+			Rate:           *rate,
+		}
+		if params.Rate == 0 {
+			params.Rate = cfg.Enricher.Reenrich.Rate
+		}
+		if *olderThan > 0 {
+			before := time.Now().Add(-*olderThan)
+			params.EnrichedBefore = &before
+		}
+
+		run, err = repo.CreateReenrichRun(ctx, params)
+	}
+	if err != nil {
+		logger.Error("can't get re-enrichment run", slog.String("[ERROR]", err.Error()))
+		os.Exit(1)
+	}
+
+	if *resume != 0 {
+		// a queued or running run is executed by the service or another command.
+		if err := services.CheckResumable(run); err != nil {
+			logger.Error("can't resume run", slog.String("[ERROR]", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	if err := service.RunReenrich(ctx, run, cfg.Enricher.Reenrich.BatchSize); err != nil {
+		logger.Error("re-enrichment run failed", slog.String("[ERROR]", err.Error()))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(run)
+
+	if run.State != models.RunDone {
+		os.Exit(1)
+	}
+}
@@ -23,6 +23,185 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/cache/stats": {
+            "get": {
+                "description": "get cache hit and miss counters of every enrichment provider",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get enrichment cache stats",
+                "operationId": "cache-stats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.CacheStats"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/enrich": {
+            "post": {
+                "description": "enrich NSP or a list of them without saving, results of a list are in its order",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "person"
+                ],
+                "summary": "Preview enrichment",
+                "operationId": "enrich",
+                "parameters": [
+                    {
+                        "description": "name, surname, patronymic, optional country hint, or a list of them",
+                        "name": "RawPerson",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RawPerson"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "person, or []response.EnrichItemResponse for a list",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Person"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "get circuit breaker state of every enrichment provider",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get enrichment providers health",
+                "operationId": "health",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.ProviderHealth"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "get state of async enrichment job and id of its person",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "person"
+                ],
+                "summary": "Get enrichment job",
+                "operationId": "get-job",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Job"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
         "/person": {
             "put": {
                 "description": "update person",
@@ -64,7 +243,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "get NSP to enrich it and add",
+                "description": "get NSP to enrich it and add, in async mode the person is saved at once and enriched by a job",
                 "consumes": [
                     "application/json"
                 ],
@@ -78,13 +257,19 @@ const docTemplate = `{
                 "operationId": "add-person",
                 "parameters": [
                     {
-                        "description": "name, surname, patronymic",
+                        "description": "name, surname, patronymic, optional country hint",
                         "name": "RawPerson",
                         "in": "body",
                         "required": true,
                         "schema": {
                             "$ref": "#/definitions/models.RawPerson"
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "enrich asynchronously, defaults to the service mode",
+                        "name": "async",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -94,6 +279,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.Response"
                         }
                     },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Job"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -160,25 +363,160 @@ const docTemplate = `{
                         "required": true
                     }
                 ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Change name, surname, patronymic, age, gender or nationality of person by JSON Merge Patch (RFC 7396)\nor JSON Patch (RFC 6902) of its top-level fields, only changed fields are written.\nA null or removed field is cleared, name and surname can't be cleared.\nAge must not exceed 150, name and surname 100 characters, patronymic 200 characters.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "person"
+                ],
+                "summary": "Partially update person in storage",
+                "operationId": "patch-person",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch object or array of JSON Patch operations",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Person"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "JSON Patch test operation failed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
+                    }
+                }
+            }
+        },
+        "/person/{id}/enrichment": {
+            "get": {
+                "description": "get provider calls made to enrich person with their raw responses",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "person"
+                ],
+                "summary": "Get enrichment log of person",
+                "operationId": "get-person-enrichment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.EnrichmentCall"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
                     }
                 }
             }
         },
         "/persons": {
             "get": {
-                "description": "Get a list of persons based on query parameters",
+                "description": "Get a list of persons based on query parameters. Pages are selected by page number,\nor by cursor if cursor parameter is set: pass an empty cursor to get the first page,\nthen next_cursor or prev_cursor of the response along with the same filters and sort.\nCursor pagination sorts by a single field of id, name, surname, age or enriched_at.",
                 "consumes": [
                     "application/json"
                 ],
@@ -193,18 +531,195 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Id of page of results",
+                        "default": 1,
+                        "description": "Number of page of results",
                         "name": "page",
-                        "in": "query",
-                        "required": true
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of page, switches to cursor pagination",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of persons in page, limited by configured maximum",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated fields to sort by, prefixed with - for descending order, e.g. age,-surname",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name, case-insensitive",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exact",
+                            "prefix",
+                            "contains"
+                        ],
+                        "type": "string",
+                        "default": "exact",
+                        "description": "Match of name",
+                        "name": "name_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Surname, case-insensitive",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exact",
+                            "prefix",
+                            "contains"
+                        ],
+                        "type": "string",
+                        "default": "exact",
+                        "description": "Match of surname",
+                        "name": "surname_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patronymic, case-insensitive",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exact",
+                            "prefix",
+                            "contains"
+                        ],
+                        "type": "string",
+                        "default": "exact",
+                        "description": "Match of patronymic",
+                        "name": "patronymic_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Min age, inclusive",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max age, inclusive",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated genders, e.g. male,female",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated country IDs of nationality, e.g. RU,UA",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Country ID from nationality distribution",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Min probability of country",
+                        "name": "country_probability",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Page by number, or response.CursorResponse by cursor",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/response.PersonsResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
+                    }
+                }
+            },
+            "post": {
+                "description": "get a list of NSP to enrich and add them, results are in the order of the list",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "person"
+                ],
+                "summary": "Add a bulk of persons",
+                "operationId": "add-persons",
+                "parameters": [
+                    {
+                        "description": "list of name, surname, patronymic",
+                        "name": "RawPersons",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RawPerson"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/response.BulkItemResponse"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -214,9 +729,387 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
+        },
+        "/persons/search": {
+            "get": {
+                "description": "Fuzzy search of persons by name, surname and patronymic, the most similar first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "person"
+                ],
+                "summary": "Search persons by name",
+                "operationId": "search-persons",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name, surname, patronymic or a part of full name, e.g. Ivanof",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of persons, limited by configured maximum",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.SearchResult"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/quota": {
+            "get": {
+                "description": "get remaining request quota of every enrichment provider",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get enrichment API quotas",
+                "operationId": "quotas",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.QuotaStatus"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/reenrich": {
+            "post": {
+                "description": "re-enrich persons enriched before the time, by the provider or with low gender or nationality probability",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Start re-enrichment",
+                "operationId": "start-reenrich",
+                "parameters": [
+                    {
+                        "description": "filter and rate in persons per second",
+                        "name": "params",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReenrichParams"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.ReenrichRun"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/reenrich/{id}": {
+            "get": {
+                "description": "get state and progress of re-enrichment run with numbers of changed values",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get re-enrichment run",
+                "operationId": "get-reenrich",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Run ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.ReenrichRun"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/reenrich/{id}/resume": {
+            "post": {
+                "description": "continue stopped or failed re-enrichment run from its last person",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Resume re-enrichment run",
+                "operationId": "resume-reenrich",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Run ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.ReenrichRun"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
     "definitions": {
+        "models.CacheStats": {
+            "type": "object",
+            "properties": {
+                "hits": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "misses": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "genderize"
+                }
+            }
+        },
+        "models.Country": {
+            "type": "object",
+            "properties": {
+                "country_id": {
+                    "type": "string",
+                    "example": "RU"
+                },
+                "probability": {
+                    "type": "number",
+                    "example": 0.63
+                }
+            }
+        },
+        "models.EnrichmentCall": {
+            "type": "object",
+            "properties": {
+                "attribute": {
+                    "type": "string",
+                    "example": "gender"
+                },
+                "cached": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "latency_ms": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "person_id": {
+                    "type": "integer",
+                    "example": 11
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "genderize"
+                },
+                "request_url": {
+                    "type": "string",
+                    "example": "https://api.genderize.io/?name=Ivan"
+                },
+                "response_body": {
+                    "type": "string",
+                    "example": "{\"count\":1024,\"name\":\"Ivan\",\"gender\":\"male\",\"probability\":0.99}"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "models.Job": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "person_id": {
+                    "type": "integer",
+                    "example": 11
+                },
+                "state": {
+                    "type": "string",
+                    "example": "done"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
         "models.Person": {
             "type": "object",
             "properties": {
@@ -224,10 +1117,43 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 45
                 },
+                "age_count": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "age_source": {
+                    "type": "string",
+                    "example": "agify"
+                },
+                "countries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Country"
+                    }
+                },
+                "enriched_at": {
+                    "type": "string"
+                },
+                "enrichment_status": {
+                    "type": "string",
+                    "example": "complete"
+                },
                 "gender": {
                     "type": "string",
                     "example": "male"
                 },
+                "gender_count": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "gender_probability": {
+                    "type": "number",
+                    "example": 0.99
+                },
+                "gender_source": {
+                    "type": "string",
+                    "example": "genderize,offline-gender"
+                },
                 "id": {
                     "type": "integer",
                     "example": 11
@@ -240,19 +1166,100 @@ const docTemplate = `{
                     "type": "string",
                     "example": "RU"
                 },
+                "nationality_count": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "nationality_probability": {
+                    "type": "number",
+                    "example": 0.63
+                },
+                "nationality_source": {
+                    "type": "string",
+                    "example": "nationalize"
+                },
                 "patronymic": {
                     "type": "string",
                     "example": "Ivanovich"
                 },
+                "pending_attributes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "gender"
+                    ]
+                },
                 "surname": {
                     "type": "string",
                     "example": "Ivanov"
                 }
             }
         },
+        "models.ProviderHealth": {
+            "type": "object",
+            "properties": {
+                "attribute": {
+                    "type": "string",
+                    "example": "gender"
+                },
+                "failures": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "opened_at": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "genderize"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "closed"
+                }
+            }
+        },
+        "models.QuotaStatus": {
+            "type": "object",
+            "properties": {
+                "exhausted": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "floor": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "known": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "agify"
+                },
+                "remaining": {
+                    "type": "integer",
+                    "example": 990
+                },
+                "reset": {
+                    "type": "string"
+                }
+            }
+        },
         "models.RawPerson": {
             "type": "object",
             "properties": {
+                "country": {
+                    "type": "string",
+                    "example": "RU"
+                },
                 "name": {
                     "type": "string",
                     "example": "Petr"
@@ -267,6 +1274,223 @@ const docTemplate = `{
                 }
             }
         },
+        "models.ReenrichParams": {
+            "type": "object",
+            "properties": {
+                "enriched_before": {
+                    "type": "string"
+                },
+                "max_probability": {
+                    "type": "number",
+                    "example": 0.8
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "genderize"
+                },
+                "rate": {
+                    "type": "number",
+                    "example": 5
+                }
+            }
+        },
+        "models.ReenrichRun": {
+            "type": "object",
+            "properties": {
+                "age_changed": {
+                    "type": "integer",
+                    "example": 11
+                },
+                "changed": {
+                    "type": "integer",
+                    "example": 17
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "enriched_before": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "gender_changed": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "heartbeat_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "last_id": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "max_probability": {
+                    "type": "number",
+                    "example": 0.8
+                },
+                "nationality_changed": {
+                    "type": "integer",
+                    "example": 6
+                },
+                "processed": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "genderize"
+                },
+                "rate": {
+                    "type": "number",
+                    "example": 5
+                },
+                "state": {
+                    "type": "string",
+                    "example": "running"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SearchResult": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer",
+                    "example": 45
+                },
+                "age_count": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "age_source": {
+                    "type": "string",
+                    "example": "agify"
+                },
+                "countries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Country"
+                    }
+                },
+                "enriched_at": {
+                    "type": "string"
+                },
+                "enrichment_status": {
+                    "type": "string",
+                    "example": "complete"
+                },
+                "gender": {
+                    "type": "string",
+                    "example": "male"
+                },
+                "gender_count": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "gender_probability": {
+                    "type": "number",
+                    "example": 0.99
+                },
+                "gender_source": {
+                    "type": "string",
+                    "example": "genderize,offline-gender"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 11
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Ivan"
+                },
+                "nationality": {
+                    "type": "string",
+                    "example": "RU"
+                },
+                "nationality_count": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "nationality_probability": {
+                    "type": "number",
+                    "example": 0.63
+                },
+                "nationality_source": {
+                    "type": "string",
+                    "example": "nationalize"
+                },
+                "patronymic": {
+                    "type": "string",
+                    "example": "Ivanovich"
+                },
+                "pending_attributes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "gender"
+                    ]
+                },
+                "score": {
+                    "type": "number",
+                    "example": 0.58
+                },
+                "surname": {
+                    "type": "string",
+                    "example": "Ivanov"
+                }
+            }
+        },
+        "response.BulkItemResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "response.PersonsResponse": {
+            "type": "object",
+            "properties": {
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "page_count": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "page_size": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "persons": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Person"
+                    }
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
         "response.Response": {
             "type": "object",
             "properties": {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/binaryty/enricher-service/internal/config"
+	"github.com/binaryty/enricher-service/internal/consumer"
 	"github.com/binaryty/enricher-service/internal/router"
 	"github.com/binaryty/enricher-service/internal/services/enricher"
 	services "github.com/binaryty/enricher-service/internal/services/people"
@@ -15,13 +16,16 @@ import (
 )
 
 type App struct {
-	l *slog.Logger
-	e *echo.Echo
-	r *router.Router
+	l      *slog.Logger
+	e      *echo.Echo
+	r      *router.Router
+	cancel context.CancelFunc
 }
 
 func New(cfg *config.Config, log *slog.Logger) *App {
-	app := App{}
+	app := App{
+		l: log,
+	}
 
 	repo, err := storage.New(cfg)
 	if err != nil {
@@ -29,11 +33,43 @@ func New(cfg *config.Config, log *slog.Logger) *App {
 		os.Exit(1)
 	}
 
-	enricherSrv := enricher.New(cfg)
+	enricherSrv, err := enricher.New(cfg, log)
+	if err != nil {
+		log.Error("can't initialize enricher", slog.String("[ERROR]", err.Error()))
+		os.Exit(1)
+	}
+
+	service := services.New(log, repo, enricherSrv, services.WithBulkConcurrency(cfg.Enricher.BulkWorkers))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.cancel = cancel
+
+	if cfg.Enricher.Mode == enricher.ModePartial {
+		go service.RunPendingWorker(ctx, cfg.Enricher.Pending.Interval, cfg.Enricher.Pending.BatchSize,
+			cfg.Enricher.Pending.MaxAttempts)
+	}
+
+	if cfg.Enricher.Async.Enabled {
+		service.StartJobWorkers(ctx, cfg.Enricher.Async.Workers, cfg.Enricher.Async.QueueSize, cfg.Enricher.Async.RescanInterval)
+	}
 
-	service := services.New(log, repo, enricherSrv)
+	service.StartReenrichWorker(ctx, cfg.Enricher.Reenrich.BatchSize)
 
-	app.r = router.New(service)
+	if cfg.Kafka.Enabled {
+		reader, dlq, err := consumer.Dial(cfg.Kafka)
+		if err != nil {
+			log.Error("can't initialize consumer", slog.String("[ERROR]", err.Error()))
+			os.Exit(1)
+		}
+
+		go func() {
+			if err := consumer.New(log, reader, dlq, service).Run(ctx); err != nil {
+				log.Error("consumer stopped", slog.String("[ERROR]", err.Error()))
+			}
+		}()
+	}
+
+	app.r = router.New(service, enricherSrv, cfg.Enricher.Reenrich.Rate, cfg.HTTPServer.PageSize, cfg.HTTPServer.MaxPageSize)
 
 	app.e = echo.New()
 
@@ -50,6 +86,8 @@ func (a *App) MustRun(host string) {
 }
 
 func (a *App) Stop(ctx context.Context) {
+	a.cancel()
+
 	if err := a.e.Shutdown(ctx); err != nil {
 		a.l.Error("can't stop application", slog.String("[FATAL]", err.Error()))
 		panic(err)
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	const ttl = 20 * time.Millisecond
+
+	// step kinds: s set with ttl, g get expecting want (empty want is a miss), w wait ttl.
+	type step struct {
+		kind  byte
+		key   string
+		value string
+		ttl   time.Duration
+		want  string
+	}
+
+	tests := []struct {
+		name     string
+		capacity int
+		steps    []step
+	}{
+		{
+			name:     "get after set",
+			capacity: 2,
+			steps:    []step{{kind: 's', key: "a", value: "1"}, {kind: 'g', key: "a", want: "1"}, {kind: 'g', key: "b"}},
+		},
+		{
+			name:     "set replaces value",
+			capacity: 2,
+			steps:    []step{{kind: 's', key: "a", value: "1"}, {kind: 's', key: "a", value: "2"}, {kind: 'g', key: "a", want: "2"}},
+		},
+		{
+			name:     "evicts least recently set",
+			capacity: 2,
+			steps: []step{
+				{kind: 's', key: "a", value: "1"}, {kind: 's', key: "b", value: "2"}, {kind: 's', key: "c", value: "3"},
+				{kind: 'g', key: "a"}, {kind: 'g', key: "b", want: "2"}, {kind: 'g', key: "c", want: "3"},
+			},
+		},
+		{
+			name:     "get refreshes entry",
+			capacity: 2,
+			steps: []step{
+				{kind: 's', key: "a", value: "1"}, {kind: 's', key: "b", value: "2"}, {kind: 'g', key: "a", want: "1"},
+				{kind: 's', key: "c", value: "3"}, {kind: 'g', key: "b"}, {kind: 'g', key: "a", want: "1"},
+			},
+		},
+		{
+			name:     "replace refreshes entry",
+			capacity: 2,
+			steps: []step{
+				{kind: 's', key: "a", value: "1"}, {kind: 's', key: "b", value: "2"}, {kind: 's', key: "a", value: "3"},
+				{kind: 's', key: "c", value: "4"}, {kind: 'g', key: "b"}, {kind: 'g', key: "a", want: "3"},
+			},
+		},
+		{
+			name:     "unbounded without capacity",
+			capacity: 0,
+			steps: []step{
+				{kind: 's', key: "a", value: "1"}, {kind: 's', key: "b", value: "2"}, {kind: 's', key: "c", value: "3"},
+				{kind: 'g', key: "a", want: "1"},
+			},
+		},
+		{
+			name:     "expires after ttl",
+			capacity: 2,
+			steps: []step{
+				{kind: 's', key: "a", value: "1", ttl: ttl}, {kind: 'g', key: "a", want: "1"}, {kind: 'w'}, {kind: 'g', key: "a"},
+			},
+		},
+		{
+			name:     "never expires without ttl",
+			capacity: 2,
+			steps:    []step{{kind: 's', key: "a", value: "1"}, {kind: 'w'}, {kind: 'g', key: "a", want: "1"}},
+		},
+		{
+			name:     "replace resets ttl",
+			capacity: 2,
+			steps: []step{
+				{kind: 's', key: "a", value: "1", ttl: ttl}, {kind: 's', key: "a", value: "2"}, {kind: 'w'},
+				{kind: 'g', key: "a", want: "2"},
+			},
+		},
+		{
+			name:     "expired entry frees room",
+			capacity: 2,
+			steps: []step{
+				{kind: 's', key: "a", value: "1", ttl: ttl}, {kind: 's', key: "b", value: "2"}, {kind: 'w'},
+				{kind: 'g', key: "a"}, {kind: 's', key: "c", value: "3"}, {kind: 'g', key: "b", want: "2"},
+				{kind: 'g', key: "c", want: "3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := NewLRU(tt.capacity)
+
+			for i, s := range tt.steps {
+				switch s.kind {
+				case 's':
+					if err := c.Set(ctx, s.key, []byte(s.value), s.ttl); err != nil {
+						t.Fatalf("step %d: Set(%s) error = %v", i, s.key, err)
+					}
+				case 'g':
+					value, ok, err := c.Get(ctx, s.key)
+					if err != nil {
+						t.Fatalf("step %d: Get(%s) error = %v", i, s.key, err)
+					}
+					if ok != (s.want != "") || string(value) != s.want {
+						t.Fatalf("step %d: Get(%s) = %q, %v, want %q", i, s.key, value, ok, s.want)
+					}
+				case 'w':
+					time.Sleep(ttl + 5*time.Millisecond)
+				}
+			}
+		})
+	}
+}
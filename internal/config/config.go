@@ -1,12 +1,16 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
@@ -21,6 +25,42 @@ const (
 	DefaultGenderAPI      = "https://api.genderize.io"
 	DefaultNationalityAPI = "https://api.nationalize.io"
 	DefaultHTTPAddress    = "localhost:8082"
+	DefaultProviders      = "agify,genderize,nationalize"
+	DefaultTopCountries   = 5
+	DefaultCallTimeout    = 10 * time.Second
+	DefaultBulkWorkers    = 8
+	DefaultRetryAttempts  = 3
+	DefaultRetryBaseDelay = 100 * time.Millisecond
+	DefaultRetryMaxDelay  = 2 * time.Second
+	DefaultRetryJitter    = 0.2
+	DefaultRetryStatuses  = "429,502,503,504"
+	DefaultBreakerFails   = 5
+	DefaultBreakerCool    = 30 * time.Second
+	DefaultEnrichMode     = "strict"
+	DefaultPendingEvery   = time.Minute
+	DefaultPendingBatch   = 50
+	DefaultPendingTries   = 5
+	DefaultCacheBackend   = "memory"
+	DefaultCacheSize      = 10000
+	DefaultCacheTTL       = 24 * time.Hour
+	DefaultRedisAddr      = "localhost:6379"
+	DefaultBatchSize      = 10
+	DefaultBatchWindow    = 20 * time.Millisecond
+	DefaultBatchTimeout   = 10 * time.Second
+	DefaultDataset        = "./data/names.csv"
+	DefaultStrategy       = "fallback"
+	DefaultTransliterate  = true
+	DefaultAsyncWorkers   = 4
+	DefaultAsyncQueue     = 100
+	DefaultAsyncRescan    = 30 * time.Second
+	DefaultKafkaBrokers   = "localhost:9092"
+	DefaultKafkaTopic     = "persons"
+	DefaultKafkaGroupID   = "enricher-service"
+	DefaultKafkaDLQTopic  = "persons-failed"
+	DefaultReenrichRate   = 5
+	DefaultReenrichBatch  = 100
+	DefaultPageSize       = 10
+	DefaultMaxPageSize    = 100
 )
 
 type Config struct {
@@ -32,17 +72,120 @@ type Config struct {
 	DBName     string     `yaml:"db_name" env-default:"people_db"`
 	DBSSLMode  string     `yaml:"db_ssl_mode" env-default:"disable"`
 	API        API        `yaml:"api"`
+	Enricher   Enricher   `yaml:"enricher"`
 	HTTPServer HTTPServer `yaml:"http_server"`
+	Kafka      Kafka      `yaml:"kafka"`
 }
 
 type HTTPServer struct {
-	Address string `yaml:"address" env-default:"localhost:8080"`
+	Address     string `yaml:"address" env-default:"localhost:8080"`
+	PageSize    int    `yaml:"page_size" env-default:"10"`
+	MaxPageSize int    `yaml:"max_page_size" env-default:"100"`
+}
+
+type Kafka struct {
+	Enabled         bool     `yaml:"enabled" env-default:"false"`
+	Brokers         []string `yaml:"brokers" env-default:"localhost:9092"`
+	Topic           string   `yaml:"topic" env-default:"persons"`
+	GroupID         string   `yaml:"group_id" env-default:"enricher-service"`
+	DeadLetterTopic string   `yaml:"dead_letter_topic" env-default:"persons-failed"`
 }
 
 type API struct {
-	Age         string `yaml:"age"`
-	Gender      string `yaml:"gender"`
-	Nationality string `yaml:"nationality"`
+	Age            string `yaml:"age"`
+	Gender         string `yaml:"gender"`
+	Nationality    string `yaml:"nationality"`
+	AgeKey         string `yaml:"age_key"`
+	GenderKey      string `yaml:"gender_key"`
+	NationalityKey string `yaml:"nationality_key"`
+}
+
+type Enricher struct {
+	Mode         string        `yaml:"mode" env-default:"strict"`
+	TwoPass      bool          `yaml:"two_pass" env-default:"false"`
+	Providers    []string      `yaml:"providers" env-default:"agify,genderize,nationalize"`
+	TopCountries int           `yaml:"top_countries" env-default:"5"`
+	CallTimeout  time.Duration `yaml:"call_timeout" env-default:"10s"`
+	BulkWorkers  int           `yaml:"bulk_workers" env-default:"8"`
+	Retry        Retry         `yaml:"retry"`
+	Breaker      Breaker       `yaml:"breaker"`
+	Pending      Pending       `yaml:"pending"`
+	Cache        Cache         `yaml:"cache"`
+	Batch        Batch         `yaml:"batch"`
+	Quota        Quota         `yaml:"quota"`
+	Offline      Offline       `yaml:"offline"`
+	Strategy     Strategy      `yaml:"strategy"`
+	Normalize    Normalize     `yaml:"normalize"`
+	Async        Async         `yaml:"async"`
+	Reenrich     Reenrich      `yaml:"reenrich"`
+}
+
+type Retry struct {
+	MaxAttempts       int           `yaml:"max_attempts" env-default:"3"`
+	BaseDelay         time.Duration `yaml:"base_delay" env-default:"100ms"`
+	MaxDelay          time.Duration `yaml:"max_delay" env-default:"2s"`
+	Jitter            float64       `yaml:"jitter" env-default:"0.2"`
+	RetryableStatuses []int         `yaml:"retryable_statuses" env-default:"429,502,503,504"`
+}
+
+type Breaker struct {
+	FailureThreshold int           `yaml:"failure_threshold" env-default:"5"`
+	CoolDown         time.Duration `yaml:"cool_down" env-default:"30s"`
+}
+
+type Pending struct {
+	Interval    time.Duration `yaml:"interval" env-default:"1m"`
+	BatchSize   int           `yaml:"batch_size" env-default:"50"`
+	MaxAttempts int           `yaml:"max_attempts" env-default:"5"`
+}
+
+type Cache struct {
+	Backend   string        `yaml:"backend" env-default:"memory"`
+	Size      int           `yaml:"size" env-default:"10000"`
+	TTL       time.Duration `yaml:"ttl" env-default:"24h"`
+	RedisAddr string        `yaml:"redis_addr" env-default:"localhost:6379"`
+	RedisPass string        `yaml:"redis_pass"`
+	RedisDB   int           `yaml:"redis_db" env-default:"0"`
+}
+
+type Batch struct {
+	Enabled bool          `yaml:"enabled" env-default:"false"`
+	Size    int           `yaml:"size" env-default:"10"`
+	Window  time.Duration `yaml:"window" env-default:"20ms"`
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+type Quota struct {
+	Floor int  `yaml:"floor" env-default:"0"`
+	Queue bool `yaml:"queue" env-default:"false"`
+}
+
+type Offline struct {
+	Dataset string `yaml:"dataset" env-default:"./data/names.csv"`
+}
+
+type Reenrich struct {
+	Rate      float64 `yaml:"rate" env-default:"5"`
+	BatchSize int     `yaml:"batch_size" env-default:"100"`
+}
+
+type Async struct {
+	Enabled        bool          `yaml:"enabled" env-default:"false"`
+	Workers        int           `yaml:"workers" env-default:"4"`
+	QueueSize      int           `yaml:"queue_size" env-default:"100"`
+	RescanInterval time.Duration `yaml:"rescan_interval" env-default:"30s"`
+}
+
+type Normalize struct {
+	Transliterate bool   `yaml:"transliterate" env-default:"true"`
+	Diminutives   string `yaml:"diminutives"`
+}
+
+type Strategy struct {
+	Default        string             `yaml:"default" env-default:"fallback"`
+	Attributes     map[string]string  `yaml:"attributes"`
+	MinProbability float64            `yaml:"min_probability" env-default:"0"`
+	Weights        map[string]float64 `yaml:"weights"`
 }
 
 // MakePGURL make a connection for postgres.
@@ -57,6 +200,50 @@ func (c *Config) MakePGURL() string {
 	)
 }
 
+// Validate checks durations and counts of config, it returns all invalid values joined.
+func (c *Config) Validate() error {
+	var errs []error
+
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	e := c.Enricher
+
+	check(e.TopCountries >= 0, "enricher.top_countries must not be negative, got %d", e.TopCountries)
+	check(e.CallTimeout > 0, "enricher.call_timeout must be positive, got %s", e.CallTimeout)
+	check(e.BulkWorkers > 0, "enricher.bulk_workers must be positive, got %d", e.BulkWorkers)
+	check(e.Retry.MaxAttempts >= 1, "enricher.retry.max_attempts must be at least 1, got %d", e.Retry.MaxAttempts)
+	check(e.Retry.BaseDelay >= 0, "enricher.retry.base_delay must not be negative, got %s", e.Retry.BaseDelay)
+	check(e.Retry.MaxDelay >= 0, "enricher.retry.max_delay must not be negative, got %s", e.Retry.MaxDelay)
+	check(e.Retry.Jitter >= 0 && e.Retry.Jitter <= 1, "enricher.retry.jitter must be between 0 and 1, got %g", e.Retry.Jitter)
+	check(e.Breaker.FailureThreshold >= 0, "enricher.breaker.failure_threshold must not be negative, got %d", e.Breaker.FailureThreshold)
+	check(e.Breaker.CoolDown >= 0, "enricher.breaker.cool_down must not be negative, got %s", e.Breaker.CoolDown)
+	check(e.Pending.Interval > 0, "enricher.pending.interval must be positive, got %s", e.Pending.Interval)
+	check(e.Pending.BatchSize > 0, "enricher.pending.batch_size must be positive, got %d", e.Pending.BatchSize)
+	check(e.Pending.MaxAttempts >= 1, "enricher.pending.max_attempts must be at least 1, got %d", e.Pending.MaxAttempts)
+	check(e.Cache.Size > 0, "enricher.cache.size must be positive, got %d", e.Cache.Size)
+	check(e.Cache.TTL >= 0, "enricher.cache.ttl must not be negative, got %s", e.Cache.TTL)
+	check(e.Batch.Size > 0, "enricher.batch.size must be positive, got %d", e.Batch.Size)
+	check(e.Batch.Window >= 0, "enricher.batch.window must not be negative, got %s", e.Batch.Window)
+	check(e.Batch.Timeout > 0, "enricher.batch.timeout must be positive, got %s", e.Batch.Timeout)
+	check(e.Quota.Floor >= 0, "enricher.quota.floor must not be negative, got %d", e.Quota.Floor)
+	check(e.Strategy.MinProbability >= 0 && e.Strategy.MinProbability <= 1,
+		"enricher.strategy.min_probability must be between 0 and 1, got %g", e.Strategy.MinProbability)
+	check(e.Async.Workers > 0, "enricher.async.workers must be positive, got %d", e.Async.Workers)
+	check(e.Async.QueueSize > 0, "enricher.async.queue_size must be positive, got %d", e.Async.QueueSize)
+	check(e.Async.RescanInterval > 0, "enricher.async.rescan_interval must be positive, got %s", e.Async.RescanInterval)
+	check(e.Reenrich.Rate >= 0, "enricher.reenrich.rate must not be negative, got %g", e.Reenrich.Rate)
+	check(e.Reenrich.BatchSize > 0, "enricher.reenrich.batch_size must be positive, got %d", e.Reenrich.BatchSize)
+	check(c.HTTPServer.PageSize > 0, "http_server.page_size must be positive, got %d", c.HTTPServer.PageSize)
+	check(c.HTTPServer.MaxPageSize >= c.HTTPServer.PageSize,
+		"http_server.max_page_size must not be less than page_size, got %d", c.HTTPServer.MaxPageSize)
+
+	return errors.Join(errs...)
+}
+
 // MustLoad load config from config file and panic if not set.
 func MustLoad() *Config {
 	path := fetchConfigPath()
@@ -80,6 +267,10 @@ func MustLoadByPath(path string) *Config {
 		panic("failed to read config:" + err.Error())
 	}
 
+	if err := cfg.Validate(); err != nil {
+		panic("invalid config: " + err.Error())
+	}
+
 	return &cfg
 }
 
@@ -102,7 +293,7 @@ func MustLoadFromEnv() *Config {
 		log.Fatalf(".env file not founf")
 	}
 
-	return &Config{
+	cfg := &Config{
 		Env:       getEnv("ENV", DefaultEnv),
 		DBHost:    getEnv("DB_HOST", DefaultDBHost),
 		DBPort:    getEnv("DB_PORT", DefaultDBPort),
@@ -111,14 +302,97 @@ func MustLoadFromEnv() *Config {
 		DBName:    getEnv("DB_NAME", DefaultDBName),
 		DBSSLMode: getEnv("DB_SSL_MODE", DefaultDBSSLMode),
 		API: API{
-			Age:         getEnv("AGE_API", DefaultAgeAPI),
-			Gender:      getEnv("GENDER_API", DefaultGenderAPI),
-			Nationality: getEnv("NATIONALITY_API", DefaultNationalityAPI),
+			Age:            getEnv("AGE_API", DefaultAgeAPI),
+			Gender:         getEnv("GENDER_API", DefaultGenderAPI),
+			Nationality:    getEnv("NATIONALITY_API", DefaultNationalityAPI),
+			AgeKey:         getEnv("AGE_API_KEY", ""),
+			GenderKey:      getEnv("GENDER_API_KEY", ""),
+			NationalityKey: getEnv("NATIONALITY_API_KEY", ""),
+		},
+		Enricher: Enricher{
+			Mode:         getEnv("ENRICH_MODE", DefaultEnrichMode),
+			TwoPass:      getEnvBool("ENRICH_TWO_PASS", false),
+			Providers:    getEnvList("ENRICH_PROVIDERS", DefaultProviders),
+			TopCountries: getEnvInt("ENRICH_TOP_COUNTRIES", DefaultTopCountries),
+			CallTimeout:  getEnvDuration("ENRICH_CALL_TIMEOUT", DefaultCallTimeout),
+			BulkWorkers:  getEnvInt("ENRICH_BULK_WORKERS", DefaultBulkWorkers),
+			Retry: Retry{
+				MaxAttempts:       getEnvInt("RETRY_MAX_ATTEMPTS", DefaultRetryAttempts),
+				BaseDelay:         getEnvDuration("RETRY_BASE_DELAY", DefaultRetryBaseDelay),
+				MaxDelay:          getEnvDuration("RETRY_MAX_DELAY", DefaultRetryMaxDelay),
+				Jitter:            getEnvFloat("RETRY_JITTER", DefaultRetryJitter),
+				RetryableStatuses: getEnvIntList("RETRY_STATUSES", DefaultRetryStatuses),
+			},
+			Breaker: Breaker{
+				FailureThreshold: getEnvInt("BREAKER_FAILURE_THRESHOLD", DefaultBreakerFails),
+				CoolDown:         getEnvDuration("BREAKER_COOL_DOWN", DefaultBreakerCool),
+			},
+			Pending: Pending{
+				Interval:    getEnvDuration("PENDING_INTERVAL", DefaultPendingEvery),
+				BatchSize:   getEnvInt("PENDING_BATCH_SIZE", DefaultPendingBatch),
+				MaxAttempts: getEnvInt("PENDING_MAX_ATTEMPTS", DefaultPendingTries),
+			},
+			Cache: Cache{
+				Backend:   getEnv("CACHE_BACKEND", DefaultCacheBackend),
+				Size:      getEnvInt("CACHE_SIZE", DefaultCacheSize),
+				TTL:       getEnvDuration("CACHE_TTL", DefaultCacheTTL),
+				RedisAddr: getEnv("REDIS_ADDR", DefaultRedisAddr),
+				RedisPass: getEnv("REDIS_PASS", ""),
+				RedisDB:   getEnvInt("REDIS_DB", 0),
+			},
+			Batch: Batch{
+				Enabled: getEnvBool("BATCH_ENABLED", false),
+				Size:    getEnvInt("BATCH_SIZE", DefaultBatchSize),
+				Window:  getEnvDuration("BATCH_WINDOW", DefaultBatchWindow),
+				Timeout: getEnvDuration("BATCH_TIMEOUT", DefaultBatchTimeout),
+			},
+			Quota: Quota{
+				Floor: getEnvInt("QUOTA_FLOOR", 0),
+				Queue: getEnvBool("QUOTA_QUEUE", false),
+			},
+			Offline: Offline{
+				Dataset: getEnv("OFFLINE_DATASET", DefaultDataset),
+			},
+			Strategy: Strategy{
+				Default:        getEnv("STRATEGY_DEFAULT", DefaultStrategy),
+				Attributes:     getEnvMap("STRATEGY_ATTRIBUTES", ""),
+				MinProbability: getEnvFloat("STRATEGY_MIN_PROBABILITY", 0),
+				Weights:        getEnvFloatMap("STRATEGY_WEIGHTS", ""),
+			},
+			Normalize: Normalize{
+				Transliterate: getEnvBool("NORMALIZE_TRANSLITERATE", DefaultTransliterate),
+				Diminutives:   getEnv("NORMALIZE_DIMINUTIVES", ""),
+			},
+			Async: Async{
+				Enabled:        getEnvBool("ASYNC_ENABLED", false),
+				Workers:        getEnvInt("ASYNC_WORKERS", DefaultAsyncWorkers),
+				QueueSize:      getEnvInt("ASYNC_QUEUE_SIZE", DefaultAsyncQueue),
+				RescanInterval: getEnvDuration("ASYNC_RESCAN_INTERVAL", DefaultAsyncRescan),
+			},
+			Reenrich: Reenrich{
+				Rate:      getEnvFloat("REENRICH_RATE", DefaultReenrichRate),
+				BatchSize: getEnvInt("REENRICH_BATCH_SIZE", DefaultReenrichBatch),
+			},
 		},
 		HTTPServer: HTTPServer{
-			Address: getEnv("HTTP_HOST", DefaultHTTPAddress),
+			Address:     getEnv("HTTP_HOST", DefaultHTTPAddress),
+			PageSize:    getEnvInt("HTTP_PAGE_SIZE", DefaultPageSize),
+			MaxPageSize: getEnvInt("HTTP_MAX_PAGE_SIZE", DefaultMaxPageSize),
+		},
+		Kafka: Kafka{
+			Enabled:         getEnvBool("KAFKA_ENABLED", false),
+			Brokers:         getEnvList("KAFKA_BROKERS", DefaultKafkaBrokers),
+			Topic:           getEnv("KAFKA_TOPIC", DefaultKafkaTopic),
+			GroupID:         getEnv("KAFKA_GROUP_ID", DefaultKafkaGroupID),
+			DeadLetterTopic: getEnv("KAFKA_DEAD_LETTER_TOPIC", DefaultKafkaDLQTopic),
 		},
 	}
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid config: %s", err)
+	}
+
+	return cfg
 }
 
 func getEnv(key string, defaultVal string) string {
@@ -128,3 +402,91 @@ func getEnv(key string, defaultVal string) string {
 
 	return defaultVal
 }
+
+func getEnvList(key string, defaultVal string) []string {
+	var list []string
+
+	for _, item := range strings.Split(getEnv(key, defaultVal), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+func getEnvInt(key string, defaultVal int) int {
+	if val, exist := os.LookupEnv(key); exist {
+		if i, err := strconv.Atoi(val); err == nil {
+			return i
+		}
+	}
+
+	return defaultVal
+}
+
+func getEnvFloat(key string, defaultVal float64) float64 {
+	if val, exist := os.LookupEnv(key); exist {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+
+	return defaultVal
+}
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	if val, exist := os.LookupEnv(key); exist {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+
+	return defaultVal
+}
+
+func getEnvIntList(key string, defaultVal string) []int {
+	var list []int
+
+	for _, item := range getEnvList(key, defaultVal) {
+		if i, err := strconv.Atoi(item); err == nil {
+			list = append(list, i)
+		}
+	}
+
+	return list
+}
+
+func getEnvBool(key string, defaultVal bool) bool {
+	if val, exist := os.LookupEnv(key); exist {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+
+	return defaultVal
+}
+
+func getEnvMap(key string, defaultVal string) map[string]string {
+	m := make(map[string]string)
+
+	for _, item := range getEnvList(key, defaultVal) {
+		if k, v, ok := strings.Cut(item, ":"); ok {
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+
+	return m
+}
+
+func getEnvFloatMap(key string, defaultVal string) map[string]float64 {
+	m := make(map[string]float64)
+
+	for k, v := range getEnvMap(key, defaultVal) {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			m[k] = f
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{name: "local config", mutate: func(*Config) {}},
+		{name: "zero pending interval", mutate: func(c *Config) { c.Enricher.Pending.Interval = 0 }, wantErr: "pending.interval"},
+		{name: "zero pending attempts", mutate: func(c *Config) { c.Enricher.Pending.MaxAttempts = 0 }, wantErr: "pending.max_attempts"},
+		{name: "zero batch timeout", mutate: func(c *Config) { c.Enricher.Batch.Timeout = 0 }, wantErr: "batch.timeout"},
+		{name: "zero async workers", mutate: func(c *Config) { c.Enricher.Async.Workers = 0 }, wantErr: "async.workers"},
+		{name: "negative reenrich rate", mutate: func(c *Config) { c.Enricher.Reenrich.Rate = -1 }, wantErr: "reenrich.rate"},
+		{name: "jitter above 1", mutate: func(c *Config) { c.Enricher.Retry.Jitter = 2 }, wantErr: "retry.jitter"},
+		{name: "max page size below page size", mutate: func(c *Config) { c.HTTPServer.MaxPageSize = 1 }, wantErr: "max_page_size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := MustLoadByPath("../../config/local.yaml")
+			tt.mutate(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %v, want error about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/config"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/services/enricher"
+	"github.com/binaryty/enricher-service/internal/storage"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	HeaderError     = "error"
+	HeaderTopic     = "topic"
+	HeaderPartition = "partition"
+	HeaderOffset    = "offset"
+)
+
+const (
+	DefaultRetryDelay = time.Second
+	maxRetryDelay     = time.Minute
+)
+
+var (
+	ErrNoClient       = errors.New("kafka client isn't built in, build with -tags kafka")
+	ErrInvalidMessage = errors.New("invalid message")
+)
+
+// Header a message header.
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Message a message of a topic.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+}
+
+// Reader reads messages of a topic by a consumer group.
+type Reader interface {
+	FetchMessage(ctx context.Context) (Message, error)
+	CommitMessages(ctx context.Context, msgs ...Message) error
+	Close() error
+}
+
+// Writer writes messages to a topic.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...Message) error
+	Close() error
+}
+
+// PersonAdder an interface implements people service.
+type PersonAdder interface {
+	AddPerson(ctx context.Context, rawData models.RawPerson) (int64, error)
+}
+
+// dial connects to the brokers, set by the kafka client build.
+var dial = func(config.Kafka) (Reader, Writer, error) {
+	return nil, nil, ErrNoClient
+}
+
+// Dial returns reader of persons topic and writer of dead-letter topic.
+func Dial(cfg config.Kafka) (Reader, Writer, error) {
+	const op = "consumer.Dial"
+
+	r, w, err := dial(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, w, nil
+}
+
+// Consumer adds persons of topic messages and moves messages which can't be enriched
+// to the dead-letter topic.
+type Consumer struct {
+	log        *slog.Logger
+	reader     Reader
+	dlq        Writer
+	service    PersonAdder
+	retryDelay time.Duration
+}
+
+// Option configures Consumer.
+type Option func(c *Consumer)
+
+// WithRetryDelay sets the first delay before a message failed by a transient error is retried,
+// DefaultRetryDelay by default. The delay doubles with every attempt up to a minute.
+func WithRetryDelay(d time.Duration) Option {
+	return func(c *Consumer) {
+		if d > 0 {
+			c.retryDelay = d
+		}
+	}
+}
+
+// New returns a new instance of Consumer.
+func New(log *slog.Logger, reader Reader, dlq Writer, service PersonAdder, opts ...Option) *Consumer {
+	c := &Consumer{
+		log:        log,
+		reader:     reader,
+		dlq:        dlq,
+		service:    service,
+		retryDelay: DefaultRetryDelay,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Run consumes messages until ctx is done, then closes reader and writer.
+// A message is committed once its person is added or it is moved to the dead-letter topic,
+// a message failed by a transient error is retried and keeps later messages waiting.
+func (c *Consumer) Run(ctx context.Context) error {
+	const op = "consumer.Run"
+	logger := c.log.With("operation", op)
+
+	defer func() {
+		_ = c.reader.Close()
+		_ = c.dlq.Close()
+	}()
+
+	logger.Info("consumer started")
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				logger.Info("consumer stopped")
+				return nil
+			}
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := c.handleWithRetry(ctx, msg); err != nil {
+			logger.Info("consumer stopped")
+			return nil
+		}
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+}
+
+// handleWithRetry handles message until it succeeds, an error is returned only when ctx is done.
+func (c *Consumer) handleWithRetry(ctx context.Context, msg Message) error {
+	const op = "consumer.handleWithRetry"
+	logger := c.log.With("operation", op, slog.Int64("offset", msg.Offset))
+
+	for delay := c.retryDelay; ; delay = min(2*delay, maxRetryDelay) {
+		err := c.handle(ctx, msg)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		logger.Warn("can't handle message, retrying",
+			slog.Duration("delay", delay),
+			slog.String("[ERROR]", err.Error()),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// handle adds person of message or moves message to the dead-letter topic
+// if it is invalid or its person can't be enriched or stored. Transient errors are returned.
+func (c *Consumer) handle(ctx context.Context, msg Message) error {
+	const op = "consumer.handle"
+	logger := c.log.With("operation", op, slog.Int64("offset", msg.Offset))
+
+	var raw models.RawPerson
+	if err := json.Unmarshal(msg.Value, &raw); err != nil {
+		return c.deadLetter(ctx, msg, fmt.Errorf("%w: %w", ErrInvalidMessage, err))
+	}
+
+	if err := validate(raw); err != nil {
+		return c.deadLetter(ctx, msg, err)
+	}
+
+	id, err := c.service.AddPerson(ctx, raw)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if permanent(err) {
+			return c.deadLetter(ctx, msg, err)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Debug("person added from message", slog.Int64("ID", id))
+
+	return nil
+}
+
+// deadLetter publishes message to the dead-letter topic with the reason of failure.
+func (c *Consumer) deadLetter(ctx context.Context, msg Message, reason error) error {
+	c.log.Warn("moving message to dead-letter topic",
+		slog.String("operation", "consumer.deadLetter"),
+		slog.Int64("offset", msg.Offset),
+		slog.String("[ERROR]", reason.Error()),
+	)
+
+	headers := make([]Header, 0, len(msg.Headers)+4)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		Header{Key: HeaderError, Value: []byte(reason.Error())},
+		Header{Key: HeaderTopic, Value: []byte(msg.Topic)},
+		Header{Key: HeaderPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		Header{Key: HeaderOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+	)
+
+	return c.dlq.WriteMessages(ctx, Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+}
+
+// permanentErrors errors of names which providers don't know and of data which storage rejects.
+var permanentErrors = []error{enricher.ErrNameNotFound, enricher.ErrNoResult, enricher.ErrNoCountry, storage.ErrInvalidData}
+
+// permanent reports whether err means the person can't be added however many times
+// it is retried. Joined errors are permanent only if all of them are.
+func permanent(err error) bool {
+	if slices.Contains(permanentErrors, err) {
+		return true
+	}
+
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		errs := e.Unwrap()
+		for _, err := range errs {
+			if !permanent(err) {
+				return false
+			}
+		}
+
+		return len(errs) > 0
+	case interface{ Unwrap() error }:
+		return permanent(e.Unwrap())
+	}
+
+	return false
+}
+
+// validate checks required fields, lengths of names and country hint of raw person.
+func validate(raw models.RawPerson) error {
+	if strings.TrimSpace(raw.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidMessage)
+	}
+
+	if strings.TrimSpace(raw.Surname) == "" {
+		return fmt.Errorf("%w: surname is required", ErrInvalidMessage)
+	}
+
+	if utf8.RuneCountInString(raw.Name) > models.MaxNameLength || utf8.RuneCountInString(raw.Surname) > models.MaxNameLength {
+		return fmt.Errorf("%w: name and surname must not exceed %d characters", ErrInvalidMessage, models.MaxNameLength)
+	}
+
+	if utf8.RuneCountInString(raw.Patronymic) > models.MaxPatronymicLength {
+		return fmt.Errorf("%w: patronymic must not exceed %d characters", ErrInvalidMessage, models.MaxPatronymicLength)
+	}
+
+	if raw.Country != "" && !models.IsCountryCode(raw.Country) {
+		return fmt.Errorf("%w: country must be ISO 3166-1 alpha-2 code", ErrInvalidMessage)
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package consumer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/consumer"
+	"github.com/binaryty/enricher-service/internal/consumer/fake"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/services/enricher"
+	"github.com/binaryty/enricher-service/internal/storage"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	topic    = "persons"
+	dlqTopic = "persons-failed"
+	group    = "test"
+)
+
+// adder a consumer.PersonAdder recording added persons, failures are returned first.
+type adder struct {
+	mu       sync.Mutex
+	added    []models.RawPerson
+	calls    int
+	failures []error
+}
+
+func (a *adder) AddPerson(_ context.Context, raw models.RawPerson) (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.calls++
+	if len(a.failures) > 0 {
+		err := a.failures[0]
+		a.failures = a.failures[1:]
+		if err != nil {
+			return -1, err
+		}
+	}
+	a.added = append(a.added, raw)
+
+	return int64(len(a.added)), nil
+}
+
+// consume runs consumer of the broker until want messages are committed or the consumer stops.
+func consume(t *testing.T, b *fake.Broker, service consumer.PersonAdder, want int64) error {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := consumer.New(slog.New(slog.NewTextHandler(io.Discard, nil)), b.Reader(topic, group), b.Writer(dlqTopic), service,
+		consumer.WithRetryDelay(time.Millisecond),
+	)
+
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	deadline := time.After(time.Second)
+	for b.Committed(topic, group) < want {
+		select {
+		case err := <-done:
+			return err
+		case <-deadline:
+			t.Fatalf("committed %d messages, want %d", b.Committed(topic, group), want)
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+
+	return <-done
+}
+
+func TestConsumer(t *testing.T) {
+	b := fake.NewBroker()
+	b.Produce(topic,
+		consumer.Message{Key: []byte("1"), Value: []byte(`{"name":"Dmitriy","surname":"Ushakov","country":"RU"}`)},
+		consumer.Message{Key: []byte("2"), Value: []byte(`{"name":`)},
+		consumer.Message{Key: []byte("3"), Value: []byte(`{"name":"Anna"}`)},
+		consumer.Message{Key: []byte("4"), Value: []byte(`{"name":"Anna","surname":"Petrova","country":"Russia"}`)},
+		consumer.Message{Key: []byte("5"), Value: []byte(`{"name":"` + strings.Repeat("я", 101) + `","surname":"Petrova"}`)},
+		consumer.Message{Key: []byte("6"), Value: []byte(`{"name":"Anna","surname":"Petrova","patronymic":"` + strings.Repeat("я", 201) + `"}`)},
+		consumer.Message{Key: []byte("7"), Value: []byte(`{"name":"Anna","surname":"Petrova"}`)},
+	)
+
+	a := &adder{}
+	if err := consume(t, b, a, 7); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(a.added) != 2 || a.added[0].Name != "Dmitriy" || a.added[1].Name != "Anna" {
+		t.Errorf("added %v, want Dmitriy and Anna", a.added)
+	}
+
+	failed := b.Messages(dlqTopic)
+	if len(failed) != 5 {
+		t.Fatalf("dead-lettered %d messages, want 5", len(failed))
+	}
+
+	for i, wantOffset := range []string{"1", "2", "3", "4", "5"} {
+		headers := make(map[string]string)
+		for _, h := range failed[i].Headers {
+			headers[h.Key] = string(h.Value)
+		}
+
+		if headers[consumer.HeaderOffset] != wantOffset || headers[consumer.HeaderTopic] != topic {
+			t.Errorf("message %d headers = %v, want offset %s of %s", i, headers, wantOffset, topic)
+		}
+		if !strings.Contains(headers[consumer.HeaderError], consumer.ErrInvalidMessage.Error()) {
+			t.Errorf("message %d error = %q, want invalid message", i, headers[consumer.HeaderError])
+		}
+	}
+}
+
+func TestConsumerFailures(t *testing.T) {
+	transient := errors.New("connection refused")
+	notFound := fmt.Errorf("services.people.AddPerson: %w", fmt.Errorf("offline-age: %w: zzyzx", enricher.ErrNameNotFound))
+	invalidData := fmt.Errorf("services.people.AddPerson: %w", fmt.Errorf("storage.postgres.Create: %w: value too long", storage.ErrInvalidData))
+	noCountry := fmt.Errorf("services.people.AddPerson: %w", &enricher.CallError{Err: enricher.ErrNoCountry})
+
+	tests := []struct {
+		name      string
+		failures  []error
+		wantCalls int
+		wantAdded int
+		wantDLQ   int
+	}{
+		{name: "transient error retried", failures: []error{transient, transient}, wantCalls: 3, wantAdded: 1},
+		{name: "name not found", failures: []error{notFound}, wantCalls: 1, wantDLQ: 1},
+		{name: "no result", failures: []error{enricher.ErrNoResult}, wantCalls: 1, wantDLQ: 1},
+		{name: "no country", failures: []error{noCountry}, wantCalls: 1, wantDLQ: 1},
+		{name: "invalid data", failures: []error{invalidData}, wantCalls: 1, wantDLQ: 1},
+		{name: "not found by all providers", failures: []error{errors.Join(notFound, enricher.ErrNoResult)}, wantCalls: 1, wantDLQ: 1},
+		{name: "not found by one of providers", failures: []error{errors.Join(notFound, transient)}, wantCalls: 2, wantAdded: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := fake.NewBroker()
+			b.Produce(topic, consumer.Message{Value: []byte(`{"name":"Zzyzx","surname":"Petrova"}`)})
+
+			a := &adder{failures: tt.failures}
+			if err := consume(t, b, a, 1); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if a.calls != tt.wantCalls || len(a.added) != tt.wantAdded {
+				t.Errorf("AddPerson called %d times adding %d, want %d adding %d", a.calls, len(a.added), tt.wantCalls, tt.wantAdded)
+			}
+			if got := len(b.Messages(dlqTopic)); got != tt.wantDLQ {
+				t.Errorf("dead-lettered %d messages, want %d", got, tt.wantDLQ)
+			}
+		})
+	}
+}
+
+func TestConsumerStopsOnTransientError(t *testing.T) {
+	b := fake.NewBroker()
+	b.Produce(topic, consumer.Message{Value: []byte(`{"name":"Anna","surname":"Petrova"}`)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	a := &adder{failures: make([]error, 1000)}
+	for i := range a.failures {
+		a.failures[i] = errors.New("connection refused")
+	}
+
+	c := consumer.New(slog.New(slog.NewTextHandler(io.Discard, nil)), b.Reader(topic, group), b.Writer(dlqTopic), a,
+		consumer.WithRetryDelay(time.Millisecond),
+	)
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// the message stays uncommitted and is redelivered to the next reader.
+	if got := b.Committed(topic, group); got != 0 {
+		t.Errorf("committed %d messages, want 0", got)
+	}
+	if len(b.Messages(dlqTopic)) != 0 {
+		t.Errorf("transient failure dead-lettered")
+	}
+}
@@ -0,0 +1,158 @@
+// Package fake provides an in-process broker to run the consumer without Kafka.
+package fake
+
+import (
+	"context"
+	"errors"
+	"github.com/binaryty/enricher-service/internal/consumer"
+	"sync"
+)
+
+var ErrClosed = errors.New("closed")
+
+// Broker an in-process broker of single-partition topics with committed offsets of consumer groups.
+type Broker struct {
+	mu        sync.Mutex
+	topics    map[string][]consumer.Message
+	committed map[string]int64
+	produced  chan struct{}
+}
+
+// NewBroker returns an empty broker.
+func NewBroker() *Broker {
+	return &Broker{
+		topics:    make(map[string][]consumer.Message),
+		committed: make(map[string]int64),
+		produced:  make(chan struct{}),
+	}
+}
+
+// Produce appends messages to topic.
+func (b *Broker) Produce(topic string, msgs ...consumer.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, m := range msgs {
+		m.Topic = topic
+		m.Offset = int64(len(b.topics[topic]))
+		b.topics[topic] = append(b.topics[topic], m)
+	}
+
+	// wake up waiting readers.
+	close(b.produced)
+	b.produced = make(chan struct{})
+}
+
+// Messages returns all messages of topic.
+func (b *Broker) Messages(topic string) []consumer.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]consumer.Message(nil), b.topics[topic]...)
+}
+
+// Committed returns the offset of the next message to be read by group from topic.
+func (b *Broker) Committed(topic, groupID string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.committed[topic+"/"+groupID]
+}
+
+// Reader returns reader of topic by group starting from its committed offset.
+func (b *Broker) Reader(topic, groupID string) *Reader {
+	return &Reader{
+		broker: b,
+		topic:  topic,
+		group:  topic + "/" + groupID,
+		offset: b.Committed(topic, groupID),
+	}
+}
+
+// Writer returns writer to topic.
+func (b *Broker) Writer(topic string) *Writer {
+	return &Writer{
+		broker: b,
+		topic:  topic,
+	}
+}
+
+// Reader a consumer.Reader of the broker.
+type Reader struct {
+	broker *Broker
+	topic  string
+	group  string
+	offset int64
+	closed bool
+}
+
+// FetchMessage waits for the next message of topic.
+func (r *Reader) FetchMessage(ctx context.Context) (consumer.Message, error) {
+	for {
+		r.broker.mu.Lock()
+		if r.closed {
+			r.broker.mu.Unlock()
+			return consumer.Message{}, ErrClosed
+		}
+
+		msgs := r.broker.topics[r.topic]
+		if r.offset < int64(len(msgs)) {
+			msg := msgs[r.offset]
+			r.offset++
+			r.broker.mu.Unlock()
+
+			return msg, nil
+		}
+
+		produced := r.broker.produced
+		r.broker.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return consumer.Message{}, ctx.Err()
+		case <-produced:
+		}
+	}
+}
+
+// CommitMessages commits offsets of messages for the group.
+func (r *Reader) CommitMessages(_ context.Context, msgs ...consumer.Message) error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+
+	for _, m := range msgs {
+		if m.Offset+1 > r.broker.committed[r.group] {
+			r.broker.committed[r.group] = m.Offset + 1
+		}
+	}
+
+	return nil
+}
+
+// Close closes reader.
+func (r *Reader) Close() error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+
+	r.closed = true
+
+	return nil
+}
+
+// Writer a consumer.Writer of the broker.
+type Writer struct {
+	broker *Broker
+	topic  string
+}
+
+// WriteMessages appends messages to topic.
+func (w *Writer) WriteMessages(_ context.Context, msgs ...consumer.Message) error {
+	w.broker.Produce(w.topic, msgs...)
+
+	return nil
+}
+
+// Close does nothing.
+func (w *Writer) Close() error {
+	return nil
+}
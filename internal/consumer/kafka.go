@@ -0,0 +1,89 @@
+//go:build kafka
+
+package consumer
+
+import (
+	"context"
+	"github.com/binaryty/enricher-service/internal/config"
+	"github.com/segmentio/kafka-go"
+)
+
+func init() {
+	dial = dialKafka
+}
+
+// dialKafka connects to the brokers by kafka-go client.
+func dialKafka(cfg config.Kafka) (Reader, Writer, error) {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: cfg.Brokers,
+		GroupID: cfg.GroupID,
+		Topic:   cfg.Topic,
+	})
+
+	w := &kafka.Writer{
+		Addr:     kafka.TCP(cfg.Brokers...),
+		Topic:    cfg.DeadLetterTopic,
+		Balancer: &kafka.LeastBytes{},
+	}
+
+	return &kafkaReader{r: r}, &kafkaWriter{w: w}, nil
+}
+
+type kafkaReader struct {
+	r *kafka.Reader
+}
+
+func (r *kafkaReader) FetchMessage(ctx context.Context) (Message, error) {
+	m, err := r.r.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Key:       m.Key,
+		Value:     m.Value,
+	}
+	for _, h := range m.Headers {
+		msg.Headers = append(msg.Headers, Header{Key: h.Key, Value: h.Value})
+	}
+
+	return msg, nil
+}
+
+func (r *kafkaReader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	km := make([]kafka.Message, 0, len(msgs))
+	for _, m := range msgs {
+		km = append(km, kafka.Message{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset})
+	}
+
+	return r.r.CommitMessages(ctx, km...)
+}
+
+func (r *kafkaReader) Close() error {
+	return r.r.Close()
+}
+
+type kafkaWriter struct {
+	w *kafka.Writer
+}
+
+func (w *kafkaWriter) WriteMessages(ctx context.Context, msgs ...Message) error {
+	km := make([]kafka.Message, 0, len(msgs))
+	for _, m := range msgs {
+		// the topic is set by the writer.
+		k := kafka.Message{Key: m.Key, Value: m.Value}
+		for _, h := range m.Headers {
+			k.Headers = append(k.Headers, kafka.Header{Key: h.Key, Value: h.Value})
+		}
+		km = append(km, k)
+	}
+
+	return w.w.WriteMessages(ctx, km...)
+}
+
+func (w *kafkaWriter) Close() error {
+	return w.w.Close()
+}
@@ -0,0 +1,260 @@
+// Package fakeapi serves agify, genderize and nationalize APIs from a local dataset
+// to run and test the service without internet access.
+package fakeapi
+
+import (
+	"encoding/json"
+	"github.com/binaryty/enricher-service/internal/services/enricher"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PathAge         = "/agify/"
+	PathGender      = "/genderize/"
+	PathNationality = "/nationalize/"
+)
+
+// Options configures misbehaviour of the fake APIs.
+type Options struct {
+	// Latency is added to every response, plus a random delay up to Jitter.
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is a share of requests failed with 500.
+	ErrorRate float64
+	// TooManyRate is a share of requests refused with 429.
+	TooManyRate float64
+	// Limit is a number of names served per Window, requests are refused with 429
+	// once it is used up. There is no limit if Limit <= 0.
+	Limit  int
+	Window time.Duration
+	// Seed makes random delays and failures reproducible.
+	Seed int64
+}
+
+// Handler serves the fake APIs under PathAge, PathGender and PathNationality.
+type Handler struct {
+	dataset *enricher.Dataset
+	opts    Options
+	mux     *http.ServeMux
+
+	mu        sync.Mutex
+	rnd       *rand.Rand
+	remaining int
+	reset     time.Time
+}
+
+// NewHandler returns a handler serving names of dataset.
+func NewHandler(dataset *enricher.Dataset, opts Options) *Handler {
+	if opts.Window <= 0 {
+		opts.Window = 24 * time.Hour
+	}
+
+	h := &Handler{
+		dataset: dataset,
+		opts:    opts,
+		mux:     http.NewServeMux(),
+		rnd:     rand.New(rand.NewSource(opts.Seed)),
+	}
+
+	h.mux.HandleFunc(PathAge, h.serve(enricher.AttributeAge))
+	h.mux.HandleFunc(PathGender, h.serve(enricher.AttributeGender))
+	h.mux.HandleFunc(PathNationality, h.serve(enricher.AttributeNationality))
+
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Server a fake APIs server listening on a local port.
+type Server struct {
+	*httptest.Server
+}
+
+// NewServer starts a server serving names of dataset, it must be closed by Close.
+func NewServer(dataset *enricher.Dataset, opts Options) *Server {
+	return &Server{
+		Server: httptest.NewServer(NewHandler(dataset, opts)),
+	}
+}
+
+// AgeURL returns URL of the fake agify API.
+func (s *Server) AgeURL() string {
+	return s.URL + PathAge
+}
+
+// GenderURL returns URL of the fake genderize API.
+func (s *Server) GenderURL() string {
+	return s.URL + PathGender
+}
+
+// NationalityURL returns URL of the fake nationalize API.
+func (s *Server) NationalityURL() string {
+	return s.URL + PathNationality
+}
+
+// serve returns handler of attribute API.
+func (h *Handler) serve(attr enricher.Attribute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		names, batch := query["name[]"]
+		if !batch {
+			names = query["name"]
+		}
+		if len(names) == 0 {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": "Missing 'name' parameter"})
+			return
+		}
+
+		status, delay := h.decide(len(names), w.Header())
+		time.Sleep(delay)
+
+		switch status {
+		case http.StatusTooManyRequests:
+			writeJSON(w, status, map[string]string{"error": "Request limit reached"})
+			return
+		case http.StatusInternalServerError:
+			writeJSON(w, status, map[string]string{"error": "Internal server error"})
+			return
+		}
+
+		countryID := query.Get("country_id")
+
+		results := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			results = append(results, h.result(attr, countryID, name))
+		}
+
+		if batch {
+			writeJSON(w, http.StatusOK, results)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, results[0])
+	}
+}
+
+// decide returns response status and delay of request for n names and sets rate limit headers.
+func (h *Handler) decide(n int, header http.Header) (int, time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delay := h.opts.Latency
+	if h.opts.Jitter > 0 {
+		delay += time.Duration(h.rnd.Int63n(int64(h.opts.Jitter)))
+	}
+
+	if h.opts.Limit > 0 {
+		now := time.Now()
+		if !now.Before(h.reset) {
+			h.remaining = h.opts.Limit
+			h.reset = now.Add(h.opts.Window)
+		}
+
+		status := http.StatusOK
+		if h.remaining < n {
+			status = http.StatusTooManyRequests
+		} else {
+			h.remaining -= n
+		}
+
+		header.Set("X-Rate-Limit-Limit", strconv.Itoa(h.opts.Limit))
+		header.Set("X-Rate-Limit-Remaining", strconv.Itoa(h.remaining))
+		header.Set("X-Rate-Limit-Reset", strconv.Itoa(int(time.Until(h.reset).Seconds())))
+
+		if status != http.StatusOK {
+			return status, delay
+		}
+	}
+
+	switch p := h.rnd.Float64(); {
+	case p < h.opts.TooManyRate:
+		header.Set("Retry-After", "1")
+		return http.StatusTooManyRequests, delay
+	case p < h.opts.TooManyRate+h.opts.ErrorRate:
+		return http.StatusInternalServerError, delay
+	}
+
+	return http.StatusOK, delay
+}
+
+// result returns API response of name, values are null if the name is unknown.
+func (h *Handler) result(attr enricher.Attribute, countryID, name string) interface{} {
+	records := h.dataset.Lookup(attr, countryID, name)
+
+	var count int64
+	if len(records) > 0 {
+		count = records[0].Count
+	}
+
+	switch attr {
+	case enricher.AttributeAge:
+		res := ageResult{Count: count, Name: name, CountryID: countryID}
+		if len(records) > 0 {
+			age, _ := strconv.Atoi(records[0].Value)
+			res.Age = &age
+		}
+
+		return res
+	case enricher.AttributeGender:
+		res := genderResult{Count: count, Name: name, CountryID: countryID}
+		if len(records) > 0 {
+			res.Gender = &records[0].Value
+			res.Probability = records[0].Probability
+		}
+
+		return res
+	default:
+		res := nationalityResult{Count: count, Name: name, Countries: make([]country, 0, len(records))}
+		for _, r := range records {
+			res.Countries = append(res.Countries, country{
+				CountryID:   strings.ToUpper(r.Value),
+				Probability: r.Probability,
+			})
+		}
+
+		return res
+	}
+}
+
+type ageResult struct {
+	Count     int64  `json:"count"`
+	Name      string `json:"name"`
+	Age       *int   `json:"age"`
+	CountryID string `json:"country_id,omitempty"`
+}
+
+type genderResult struct {
+	Count       int64   `json:"count"`
+	Name        string  `json:"name"`
+	Gender      *string `json:"gender"`
+	Probability float64 `json:"probability"`
+	CountryID   string  `json:"country_id,omitempty"`
+}
+
+type country struct {
+	CountryID   string  `json:"country_id"`
+	Probability float64 `json:"probability"`
+}
+
+type nationalityResult struct {
+	Count     int64     `json:"count"`
+	Name      string    `json:"name"`
+	Countries []country `json:"country"`
+}
+
+// writeJSON writes value as JSON response with status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,228 @@
+package fakeapi
+
+import (
+	"encoding/json"
+	"github.com/binaryty/enricher-service/internal/services/enricher"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const rows = "name,attribute,value,probability,count,country_id\n" +
+	"anna,age,30,0,100,\n" +
+	"anna,gender,female,0.98,100,\n" +
+	"anna,nationality,ua,0.4,100,\n" +
+	"anna,nationality,ru,0.3,100,\n" +
+	"ivan,age,41,0,50,\n"
+
+// newServer starts fake APIs server of test dataset with opts.
+func newServer(t *testing.T, opts Options) *Server {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "names.csv")
+	if err := os.WriteFile(path, []byte(rows), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := enricher.LoadDataset(path, enricher.NewNormalizer(false, nil))
+	if err != nil {
+		t.Fatalf("LoadDataset() error = %v", err)
+	}
+
+	srv := NewServer(ds, opts)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// get requests url and decodes JSON response into v.
+func get(t *testing.T, url string, v interface{}) *http.Response {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s decode error = %v", url, err)
+	}
+
+	return resp
+}
+
+func TestServer(t *testing.T) {
+	srv := newServer(t, Options{})
+
+	var age ageResult
+	if resp := get(t, srv.AgeURL()+"?name=Anna", &age); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if age.Age == nil || *age.Age != 30 || age.Count != 100 {
+		t.Errorf("age = %+v, want 30 of 100", age)
+	}
+
+	var gender genderResult
+	get(t, srv.GenderURL()+"?name=anna", &gender)
+	if gender.Gender == nil || *gender.Gender != "female" || gender.Probability != 0.98 {
+		t.Errorf("gender = %+v, want female 0.98", gender)
+	}
+
+	var nationality nationalityResult
+	get(t, srv.NationalityURL()+"?name=anna", &nationality)
+	if len(nationality.Countries) != 2 || nationality.Countries[0].CountryID != "UA" {
+		t.Errorf("countries = %+v, want UA and RU", nationality.Countries)
+	}
+
+	var unknown ageResult
+	get(t, srv.AgeURL()+"?name=zzz", &unknown)
+	if unknown.Age != nil || unknown.Count != 0 {
+		t.Errorf("unknown age = %+v, want null of 0", unknown)
+	}
+
+	var empty nationalityResult
+	get(t, srv.NationalityURL()+"?name=zzz", &empty)
+	if empty.Countries == nil || len(empty.Countries) != 0 {
+		t.Errorf("unknown countries = %v, want empty list", empty.Countries)
+	}
+
+	var batch []ageResult
+	get(t, srv.AgeURL()+"?name[]=anna&name[]=zzz&name[]=ivan", &batch)
+	if len(batch) != 3 || batch[0].Age == nil || batch[1].Age != nil || batch[2].Age == nil || *batch[2].Age != 41 {
+		t.Errorf("batch = %+v, want anna, unknown and ivan", batch)
+	}
+
+	var missing map[string]string
+	if resp := get(t, srv.AgeURL(), &missing); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("status without name = %d, want 422", resp.StatusCode)
+	}
+}
+
+func TestServerMisbehaviour(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           Options
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{name: "errors", opts: Options{ErrorRate: 1}, wantStatus: http.StatusInternalServerError},
+		{name: "too many requests", opts: Options{TooManyRate: 1}, wantStatus: http.StatusTooManyRequests, wantRetryAfter: "1"},
+		{name: "no failures", opts: Options{ErrorRate: 0, TooManyRate: 0}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t, tt.opts)
+
+			for i := 0; i < 5; i++ {
+				var res map[string]interface{}
+				resp := get(t, srv.AgeURL()+"?name=anna", &res)
+				if resp.StatusCode != tt.wantStatus {
+					t.Fatalf("request %d status = %d, want %d", i, resp.StatusCode, tt.wantStatus)
+				}
+				if got := resp.Header.Get("Retry-After"); got != tt.wantRetryAfter {
+					t.Errorf("request %d Retry-After = %q, want %q", i, got, tt.wantRetryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestServerErrorRate(t *testing.T) {
+	srv := newServer(t, Options{ErrorRate: 0.5, Seed: 1})
+
+	failed := 0
+	for i := 0; i < 100; i++ {
+		var res map[string]interface{}
+		if resp := get(t, srv.AgeURL()+"?name=anna", &res); resp.StatusCode == http.StatusInternalServerError {
+			failed++
+		}
+	}
+
+	if failed < 30 || failed > 70 {
+		t.Errorf("failed %d of 100 requests, want about 50", failed)
+	}
+}
+
+func TestServerLimit(t *testing.T) {
+	srv := newServer(t, Options{Limit: 3, Window: time.Hour})
+
+	// steps: names requested and the expected status and remaining names.
+	steps := []struct {
+		query         string
+		wantStatus    int
+		wantRemaining string
+	}{
+		{query: "?name=anna", wantStatus: http.StatusOK, wantRemaining: "2"},
+		{query: "?name[]=anna&name[]=ivan&name[]=zzz", wantStatus: http.StatusTooManyRequests, wantRemaining: "2"},
+		{query: "?name[]=anna&name[]=ivan", wantStatus: http.StatusOK, wantRemaining: "0"},
+		{query: "?name=anna", wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+	}
+
+	for i, step := range steps {
+		var res interface{}
+		resp := get(t, srv.GenderURL()+step.query, &res)
+
+		if resp.StatusCode != step.wantStatus {
+			t.Errorf("step %d status = %d, want %d", i, resp.StatusCode, step.wantStatus)
+		}
+		if got := resp.Header.Get("X-Rate-Limit-Remaining"); got != step.wantRemaining {
+			t.Errorf("step %d remaining = %s, want %s", i, got, step.wantRemaining)
+		}
+		if got := resp.Header.Get("X-Rate-Limit-Limit"); got != "3" {
+			t.Errorf("step %d limit = %s, want 3", i, got)
+		}
+	}
+}
+
+func TestServerLimitReset(t *testing.T) {
+	const window = 50 * time.Millisecond
+
+	srv := newServer(t, Options{Limit: 1, Window: window})
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		var res interface{}
+		if resp := get(t, srv.AgeURL()+"?name=anna", &res); resp.StatusCode != want {
+			t.Fatalf("request %d status = %d, want %d", i, resp.StatusCode, want)
+		}
+	}
+
+	time.Sleep(window)
+
+	var res interface{}
+	if resp := get(t, srv.AgeURL()+"?name=anna", &res); resp.StatusCode != http.StatusOK {
+		t.Errorf("status after window = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	const latency = 30 * time.Millisecond
+
+	tests := []struct {
+		name   string
+		opts   Options
+		minDur time.Duration
+		maxDur time.Duration
+	}{
+		{name: "latency", opts: Options{Latency: latency}, minDur: latency, maxDur: time.Second},
+		{name: "latency with jitter", opts: Options{Latency: latency, Jitter: latency}, minDur: latency, maxDur: time.Second},
+		{name: "latency of failed request", opts: Options{Latency: latency, ErrorRate: 1}, minDur: latency, maxDur: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t, tt.opts)
+
+			start := time.Now()
+			var res interface{}
+			get(t, srv.AgeURL()+"?name=anna", &res)
+
+			if took := time.Since(start); took < tt.minDur || took > tt.maxDur {
+				t.Errorf("request took %v, want between %v and %v", took, tt.minDur, tt.maxDur)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type ProviderHealth struct {
+	Provider  string     `json:"provider" example:"genderize"`
+	Attribute string     `json:"attribute" example:"gender"`
+	State     string     `json:"state" example:"closed"`
+	Failures  int        `json:"failures" example:"0"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+}
+
+type CacheStats struct {
+	Provider string `json:"provider" example:"genderize"`
+	Hits     int64  `json:"hits" example:"42"`
+	Misses   int64  `json:"misses" example:"7"`
+}
+
+type QuotaStatus struct {
+	Provider  string     `json:"provider" example:"agify"`
+	Known     bool       `json:"known" example:"true"`
+	Limit     int        `json:"limit" example:"1000"`
+	Remaining int        `json:"remaining" example:"990"`
+	Floor     int        `json:"floor" example:"10"`
+	Exhausted bool       `json:"exhausted" example:"false"`
+	Reset     *time.Time `json:"reset,omitempty"`
+}
+
+type EnrichmentCall struct {
+	ID        int64     `db:"id" json:"id" example:"1"`
+	PersonID  int64     `db:"person_id" json:"person_id" example:"11"`
+	Provider  string    `db:"provider" json:"provider" example:"genderize"`
+	Attribute string    `db:"attribute" json:"attribute" example:"gender"`
+	URL       string    `db:"request_url" json:"request_url" example:"https://api.genderize.io/?name=Ivan"`
+	Status    int       `db:"status" json:"status" example:"200"`
+	LatencyMs int64     `db:"latency_ms" json:"latency_ms" example:"120"`
+	Body      string    `db:"response_body" json:"response_body" example:"{\"count\":1024,\"name\":\"Ivan\",\"gender\":\"male\",\"probability\":0.99}"`
+	Error     string    `db:"error" json:"error,omitempty"`
+	Cached    bool      `db:"cached" json:"cached" example:"false"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+type Job struct {
+	ID        int64     `db:"id" json:"id" example:"7"`
+	PersonID  int64     `db:"person_id" json:"person_id" example:"11"`
+	State     string    `db:"state" json:"state" example:"done"`
+	Country   string    `db:"country" json:"-"`
+	Error     string    `db:"error" json:"error,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
@@ -1,22 +1,189 @@
 package models
 
+import (
+	"encoding/json"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	AttributeAge         = "age"
+	AttributeGender      = "gender"
+	AttributeNationality = "nationality"
+
+	EnrichmentComplete = "complete"
+	EnrichmentPending  = "pending"
+	EnrichmentQueued   = "queued"
+	EnrichmentFailed   = "failed"
+
+	// SourceManual is a source of attributes set by patch.
+	SourceManual = "manual"
+
+	// MaxNameLength and MaxPatronymicLength are lengths of names in characters allowed by storage.
+	MaxNameLength       = 100
+	MaxPatronymicLength = 200
+
+	MatchExact    = "exact"
+	MatchPrefix   = "prefix"
+	MatchContains = "contains"
+)
+
 type Person struct {
-	ID          int64  `db:"id" json:"id" example:"11"`
-	Name        string `db:"name" json:"name" example:"Ivan"`
-	Surname     string `db:"surname" json:"surname" example:"Ivanov"`
-	Patronymic  string `db:"patronymic" json:"patronymic" example:"Ivanovich"`
-	Age         uint   `db:"age" json:"age" example:"45"`
-	Gender      string `db:"gender" json:"gender" example:"male"`
-	Nationality string `db:"nationality" json:"nationality" example:"RU"`
+	ID                     int64            `db:"id" json:"id" example:"11"`
+	Name                   string           `db:"name" json:"name" example:"Ivan"`
+	Surname                string           `db:"surname" json:"surname" example:"Ivanov"`
+	Patronymic             string           `db:"patronymic" json:"patronymic" example:"Ivanovich"`
+	Age                    uint             `db:"age" json:"age" example:"45"`
+	AgeCount               int64            `db:"age_count" json:"age_count" example:"1024"`
+	AgeSource              string           `db:"age_source" json:"age_source" example:"agify"`
+	Gender                 string           `db:"gender" json:"gender" example:"male"`
+	GenderProbability      float64          `db:"gender_probability" json:"gender_probability" example:"0.99"`
+	GenderCount            int64            `db:"gender_count" json:"gender_count" example:"1024"`
+	GenderSource           string           `db:"gender_source" json:"gender_source" example:"genderize,offline-gender"`
+	Nationality            string           `db:"nationality" json:"nationality" example:"RU"`
+	NationalityProbability float64          `db:"nationality_probability" json:"nationality_probability" example:"0.63"`
+	NationalityCount       int64            `db:"nationality_count" json:"nationality_count" example:"1024"`
+	NationalitySource      string           `db:"nationality_source" json:"nationality_source" example:"nationalize"`
+	Countries              []Country        `db:"-" json:"countries"`
+	EnrichmentStatus       string           `db:"enrichment_status" json:"enrichment_status" example:"complete"`
+	PendingAttributes      pq.StringArray   `db:"pending_attributes" json:"pending_attributes" swaggertype:"array,string" example:"gender"`
+	EnrichedAt             time.Time        `db:"enriched_at" json:"enriched_at"`
+	CountryHint            string           `db:"country_hint" json:"-"`
+	Calls                  []EnrichmentCall `db:"-" json:"-"`
+}
+
+type Country struct {
+	CountryID   string  `db:"country_id" json:"country_id" example:"RU"`
+	Probability float64 `db:"probability" json:"probability" example:"0.63"`
+}
+
+// IsCountryCode reports whether s is a two-letter ISO 3166-1 alpha-2 code in either case.
+func IsCountryCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+
+	return true
 }
 
 type RawPerson struct {
 	Name       string `json:"name" db:"name" example:"Petr"`
 	Surname    string `json:"surname" db:"surname" example:"Petrov"`
 	Patronymic string `json:"patronymic" db:"patronymic" example:"Petrovich"`
+	Country    string `json:"country,omitempty" db:"-" example:"RU"`
+}
+
+// SortFields fields of Person which persons can be sorted by.
+var SortFields = []string{
+	"id", "name", "surname", "patronymic", "age", "gender", "gender_probability",
+	"nationality", "nationality_probability", "enriched_at",
+}
+
+// CursorSortFields fields of Person which persons can be sorted by in cursor pagination,
+// storage keeps an index of every one of them along with id.
+var CursorSortFields = []string{"id", "name", "surname", "age", "enriched_at"}
+
+// SortField a field to sort persons by, in descending order if Desc.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Keyset position of a person in persons sorted by Params.KeyFields, it holds values
+// of the key fields of the person. Persons before the position are selected if Backward.
+type Keyset struct {
+	Values   []interface{}
+	Backward bool
+}
+
+// SortValue returns value of sortable field of person.
+func (p *Person) SortValue(field string) interface{} {
+	switch field {
+	case "name":
+		return p.Name
+	case "surname":
+		return p.Surname
+	case "patronymic":
+		return p.Patronymic
+	case "age":
+		return p.Age
+	case "gender":
+		return p.Gender
+	case "gender_probability":
+		return p.GenderProbability
+	case "nationality":
+		return p.Nationality
+	case "nationality_probability":
+		return p.NationalityProbability
+	case "enriched_at":
+		return p.EnrichedAt
+	default:
+		return p.ID
+	}
+}
+
+// TextFilter matches a text field case-insensitively, exactly, by prefix or by substring,
+// an empty filter matches everything.
+type TextFilter struct {
+	Value string
+	Match string
 }
 
+// Params of persons query, zero filters match everything. Age, gender and nationality
+// of not enriched persons are matched as zero values, Country matches persons with
+// the country among their nationalities with at least CountryProbability.
 type Params struct {
-	Limit  int
-	Offset int
+	Limit              int
+	Offset             int
+	Name               TextFilter
+	Surname            TextFilter
+	Patronymic         TextFilter
+	AgeMin             *uint
+	AgeMax             *uint
+	Genders            []string
+	Nationalities      []string
+	Country            string
+	CountryProbability float64
+	Sort               []SortField
+	After              *Keyset
+}
+
+// KeyFields returns sort fields up to id, persons are sorted by id last to have a total order.
+// The id follows the direction of the last sort field to keep the order scannable by an index.
+func (p Params) KeyFields() []SortField {
+	fields := make([]SortField, 0, len(p.Sort)+1)
+
+	for _, f := range p.Sort {
+		fields = append(fields, f)
+		if f.Field == "id" {
+			return fields
+		}
+	}
+
+	id := SortField{Field: "id"}
+	if len(fields) > 0 {
+		id.Desc = fields[len(fields)-1].Desc
+	}
+
+	return append(fields, id)
+}
+
+// SearchResult a person found by name search with similarity of its names to the query.
+type SearchResult struct {
+	Person
+	Score float64 `db:"score" json:"score" example:"0.58"`
+}
+
+// PatchOperation a JSON Patch (RFC 6902) operation, Value is nil if the operation has no value.
+type PatchOperation struct {
+	Op    string          `json:"op" example:"replace"`
+	Path  string          `json:"path" example:"/age"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"object"`
 }
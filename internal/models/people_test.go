@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestIsCountryCode(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{s: "RU", want: true},
+		{s: "ua", want: true},
+		{s: "Pl", want: true},
+		{s: ""},
+		{s: "R"},
+		{s: "RUS"},
+		{s: "R1"},
+		{s: "R "},
+		{s: "Ру"},
+		{s: "Я"},
+	}
+
+	for _, tt := range tests {
+		if got := IsCountryCode(tt.s); got != tt.want {
+			t.Errorf("IsCountryCode(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+const (
+	RunQueued  = "queued"
+	RunRunning = "running"
+	RunStopped = "stopped"
+	RunDone    = "done"
+	RunFailed  = "failed"
+)
+
+type ReenrichParams struct {
+	EnrichedBefore *time.Time `db:"enriched_before" json:"enriched_before,omitempty"`
+	Provider       string     `db:"provider" json:"provider,omitempty" example:"genderize"`
+	MaxProbability float64    `db:"max_probability" json:"max_probability,omitempty" example:"0.8"`
+	Rate           float64    `db:"rate" json:"rate,omitempty" example:"5"`
+}
+
+type ReenrichRun struct {
+	ReenrichParams
+	ID                 int64     `db:"id" json:"id" example:"3"`
+	State              string    `db:"state" json:"state" example:"running"`
+	LastID             int64     `db:"last_id" json:"last_id" example:"120"`
+	Processed          int64     `db:"processed" json:"processed" example:"120"`
+	Failed             int64     `db:"failed" json:"failed" example:"2"`
+	Changed            int64     `db:"changed" json:"changed" example:"17"`
+	AgeChanged         int64     `db:"age_changed" json:"age_changed" example:"11"`
+	GenderChanged      int64     `db:"gender_changed" json:"gender_changed" example:"3"`
+	NationalityChanged int64     `db:"nationality_changed" json:"nationality_changed" example:"6"`
+	Error              string    `db:"error" json:"error,omitempty"`
+	CreatedAt          time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt          time.Time `db:"updated_at" json:"updated_at"`
+	HeartbeatAt        time.Time `db:"heartbeat_at" json:"heartbeat_at"`
+}
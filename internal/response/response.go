@@ -1,23 +1,28 @@
 package response
 
 import (
+	"github.com/binaryty/enricher-service/internal/models"
 	"github.com/labstack/echo/v4"
 )
 
 type AgeResponse struct {
-	Age uint `json:"age"`
+	Age   *uint `json:"age"`
+	Count int64 `json:"count"`
 }
 
 type GenderResponse struct {
-	Gender string `json:"gender"`
+	Gender      string  `json:"gender"`
+	Probability float64 `json:"probability"`
+	Count       int64   `json:"count"`
 }
 
 type NationalityResponse struct {
 	CountryID   string  `json:"country_id"`
-	Probability float32 `json:"probability"`
+	Probability float64 `json:"probability"`
 }
 
 type NationalitysResponse struct {
+	Count     int64                 `json:"count"`
 	Countries []NationalityResponse `json:"country"`
 }
 
@@ -25,6 +30,31 @@ type IDResponse struct {
 	ID int64 `json:"id"`
 }
 
+type BulkItemResponse struct {
+	ID    int64  `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type EnrichItemResponse struct {
+	Person *models.Person `json:"person,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+type PersonsResponse struct {
+	Persons   []models.Person `json:"persons"`
+	Total     int64           `json:"total" example:"42"`
+	Page      int             `json:"page" example:"1"`
+	PageSize  int             `json:"page_size" example:"10"`
+	PageCount int64           `json:"page_count" example:"5"`
+}
+
+type CursorResponse struct {
+	Persons    []models.Person `json:"persons"`
+	PageSize   int             `json:"page_size" example:"10"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	PrevCursor string          `json:"prev_cursor,omitempty"`
+}
+
 type Response struct {
 	Code   int         `json:"code"`
 	Status string      `json:"status"`
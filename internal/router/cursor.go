@@ -0,0 +1,122 @@
+package router
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/models"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrInvalidCursor = errors.New("cursor is invalid or was issued for another sort or filters")
+	ErrCursorSort    = errors.New("cursor pagination sorts by one of id, name, surname, age, enriched_at")
+)
+
+// cursor an opaque position in persons sorted by Sort and matching filters of hash Filter.
+type cursor struct {
+	Sort     string        `json:"s"`
+	Filter   string        `json:"f"`
+	Values   []interface{} `json:"v"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+// checkCursorSort returns ErrCursorSort unless persons are sorted by a single field
+// served by an index.
+func checkCursorSort(sort []models.SortField) error {
+	if len(sort) > 1 || (len(sort) == 1 && !slices.Contains(models.CursorSortFields, sort[0].Field)) {
+		return ErrCursorSort
+	}
+
+	return nil
+}
+
+// encodeCursor returns cursor of position after or before person in persons sorted by params.
+func encodeCursor(params models.Params, person *models.Person, backward bool) string {
+	fields := params.KeyFields()
+
+	cur := cursor{
+		Sort:     formatSort(params.Sort),
+		Filter:   filterHash(params),
+		Values:   make([]interface{}, 0, len(fields)),
+		Backward: backward,
+	}
+
+	for _, f := range fields {
+		cur.Values = append(cur.Values, person.SortValue(f.Field))
+	}
+
+	data, _ := json.Marshal(cur)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns keyset of cursor issued for persons sorted by params.
+func decodeCursor(params models.Params, s string) (*models.Keyset, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// numbers are kept as strings to pass them to storage without loss of precision.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var cur cursor
+	if err := dec.Decode(&cur); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cur.Sort != formatSort(params.Sort) || cur.Filter != filterHash(params) || len(cur.Values) != len(params.KeyFields()) {
+		return nil, ErrInvalidCursor
+	}
+
+	return &models.Keyset{
+		Values:   cur.Values,
+		Backward: cur.Backward,
+	}, nil
+}
+
+// formatSort returns sort fields in the form of sort query parameter.
+func formatSort(sort []models.SortField) string {
+	items := make([]string, 0, len(sort))
+
+	for _, f := range sort {
+		if f.Desc {
+			items = append(items, "-"+f.Field)
+			continue
+		}
+		items = append(items, f.Field)
+	}
+
+	return strings.Join(items, ",")
+}
+
+// filterHash returns hash of filters of params, the order of listed values doesn't matter.
+func filterHash(params models.Params) string {
+	genders := slices.Clone(params.Genders)
+	slices.Sort(genders)
+	nationalities := slices.Clone(params.Nationalities)
+	slices.Sort(nationalities)
+
+	ageMin, ageMax := "", ""
+	if params.AgeMin != nil {
+		ageMin = fmt.Sprint(*params.AgeMin)
+	}
+	if params.AgeMax != nil {
+		ageMax = fmt.Sprint(*params.AgeMax)
+	}
+
+	data, _ := json.Marshal([]interface{}{
+		params.Name, params.Surname, params.Patronymic, ageMin, ageMax,
+		genders, nationalities, params.Country, params.CountryProbability,
+	})
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,121 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/binaryty/enricher-service/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	enrichedAt := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	person := &models.Person{ID: 9007199254740993, Name: "Anna", Age: 31, EnrichedAt: enrichedAt}
+
+	tests := []struct {
+		name     string
+		sort     []models.SortField
+		backward bool
+		want     []interface{}
+	}{
+		{name: "by id", want: []interface{}{json.Number("9007199254740993")}},
+		{name: "by name", sort: []models.SortField{{Field: "name"}}, want: []interface{}{"Anna", json.Number("9007199254740993")}},
+		{name: "by age backward", sort: []models.SortField{{Field: "age", Desc: true}}, backward: true, want: []interface{}{json.Number("31"), json.Number("9007199254740993")}},
+		{name: "by enriched at", sort: []models.SortField{{Field: "enriched_at"}}, want: []interface{}{"2024-05-01T10:30:00Z", json.Number("9007199254740993")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := models.Params{Sort: tt.sort, Genders: []string{"female"}}
+
+			keyset, err := decodeCursor(params, encodeCursor(params, person, tt.backward))
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(keyset.Values, tt.want) || keyset.Backward != tt.backward {
+				t.Errorf("keyset = %v backward %v, want %v backward %v", keyset.Values, keyset.Backward, tt.want, tt.backward)
+			}
+		})
+	}
+}
+
+func TestCursorMismatch(t *testing.T) {
+	age := uint(30)
+	issued := models.Params{
+		Sort:    []models.SortField{{Field: "age"}},
+		Name:    models.TextFilter{Value: "an", Match: models.MatchPrefix},
+		Genders: []string{"male", "female"},
+	}
+	cur := encodeCursor(issued, &models.Person{ID: 1, Age: 30}, false)
+
+	tests := []struct {
+		name    string
+		mutate  func(p *models.Params)
+		cursor  string
+		wantErr bool
+	}{
+		{name: "same params", mutate: func(*models.Params) {}},
+		{name: "genders reordered", mutate: func(p *models.Params) { p.Genders = []string{"female", "male"} }},
+		{name: "page size changed", mutate: func(p *models.Params) { p.Limit = 50 }},
+		{name: "other sort", mutate: func(p *models.Params) { p.Sort = []models.SortField{{Field: "age", Desc: true}} }, wantErr: true},
+		{name: "other name", mutate: func(p *models.Params) { p.Name.Value = "ann" }, wantErr: true},
+		{name: "other match", mutate: func(p *models.Params) { p.Name.Match = models.MatchContains }, wantErr: true},
+		{name: "age filter added", mutate: func(p *models.Params) { p.AgeMin = &age }, wantErr: true},
+		{name: "gender removed", mutate: func(p *models.Params) { p.Genders = []string{"male"} }, wantErr: true},
+		{name: "country added", mutate: func(p *models.Params) { p.Country = "RU" }, wantErr: true},
+		{name: "garbage", mutate: func(*models.Params) {}, cursor: "not a cursor", wantErr: true},
+		{name: "not json", mutate: func(*models.Params) {}, cursor: "bm90IGpzb24", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := issued
+			params.Genders = append([]string(nil), issued.Genders...)
+			tt.mutate(&params)
+
+			s := cur
+			if tt.cursor != "" {
+				s = tt.cursor
+			}
+
+			_, err := decodeCursor(params, s)
+			if tt.wantErr && !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeCursor() error = %v, want ErrInvalidCursor", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("decodeCursor() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckCursorSort(t *testing.T) {
+	tests := []struct {
+		sort    string
+		wantErr bool
+	}{
+		{sort: ""},
+		{sort: "id"},
+		{sort: "-name"},
+		{sort: "surname"},
+		{sort: "age"},
+		{sort: "-enriched_at"},
+		{sort: "gender", wantErr: true},
+		{sort: "nationality_probability", wantErr: true},
+		{sort: "patronymic", wantErr: true},
+		{sort: "age,-surname", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		sort, err := parseSort(tt.sort)
+		if err != nil {
+			t.Fatalf("parseSort(%q) error = %v", tt.sort, err)
+		}
+
+		if err := checkCursorSort(sort); (err != nil) != tt.wantErr {
+			t.Errorf("checkCursorSort(%q) = %v, want error %v", tt.sort, err, tt.wantErr)
+		}
+	}
+}
@@ -1,47 +1,104 @@
 package router
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/binaryty/enricher-service/internal/models"
 	"github.com/binaryty/enricher-service/internal/response"
+	services "github.com/binaryty/enricher-service/internal/services/people"
 	"github.com/binaryty/enricher-service/internal/storage"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"io"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	echoSwagger "github.com/swaggo/echo-swagger"
 	_ "github.com/swaggo/echo-swagger/example/docs"
 )
 
 const (
-	PageSize = 10
-
-	StatusOk         = "Ok"
-	StatusCrated     = "Successfully Created"
-	StatusInternal   = "Internal Error"
-	StatusBadRequest = "Bad Request"
-	StatusNotFound   = "Not Found"
-	StatusNoContent  = "No Content"
+	MaxBulkSize = 1000
+
+	StatusOk          = "Ok"
+	StatusCrated      = "Successfully Created"
+	StatusAccepted    = "Accepted"
+	StatusInternal    = "Internal Error"
+	StatusBadRequest  = "Bad Request"
+	StatusNotFound    = "Not Found"
+	StatusNoContent   = "No Content"
+	StatusConflict    = "Conflict"
+	StatusUnavailable = "Service Unavailable"
+	StatusUnsupported = "Unsupported Media Type"
+
+	MIMEMergePatch = "application/merge-patch+json"
+	MIMEJSONPatch  = "application/json-patch+json"
+)
+
+var (
+	ErrInvalidProbability = errors.New("probability must be between 0 and 1")
+	ErrBulkSize           = errors.New("bulk must contain from 1 to 1000 persons")
+	ErrInvalidCountry     = errors.New("country must be ISO 3166-1 alpha-2 code")
+	ErrInvalidMatch       = errors.New("match must be exact, prefix or contains")
+	ErrInvalidAge         = errors.New("age must be a non-negative integer, age_min must not exceed age_max")
+	ErrInvalidGender      = errors.New("gender must be male or female")
+	ErrInvalidPage        = errors.New("page must be a positive integer")
+	ErrInvalidPageSize    = errors.New("page_size must be a positive integer not exceeding the maximum")
+	ErrInvalidSort        = errors.New("sort must be comma-separated fields of person, prefixed with - for descending order")
+	ErrEmptyQuery         = errors.New("query must not be empty")
+	ErrMediaType          = errors.New("patch must be application/merge-patch+json or application/json-patch+json")
+	ErrInvalidRate        = errors.New("rate must be a non-negative number of persons per second")
 )
 
 type PeopleService interface {
 	AddPerson(ctx context.Context, rawData models.RawPerson) (int64, error)
+	AddPersons(ctx context.Context, rawData []models.RawPerson) ([]int64, []error)
+	Enrich(ctx context.Context, rawData models.RawPerson) (*models.Person, error)
+	EnrichAll(ctx context.Context, rawData []models.RawPerson) ([]*models.Person, []error)
+	EnqueuePerson(ctx context.Context, rawData models.RawPerson) (*models.Job, error)
+	SelectJob(ctx context.Context, id int64) (*models.Job, error)
+	Async() bool
+	StartReenrich(ctx context.Context, params models.ReenrichParams) (*models.ReenrichRun, error)
+	ResumeReenrich(ctx context.Context, id int64) (*models.ReenrichRun, error)
+	SelectReenrichRun(ctx context.Context, id int64) (*models.ReenrichRun, error)
 	SelectByID(ctx context.Context, id int64) (*models.Person, error)
+	SelectEnrichmentLog(ctx context.Context, id int64) ([]models.EnrichmentCall, error)
 	Update(ctx context.Context, params *models.Person) error
-	SelectAll(ctx context.Context, params models.Params) ([]models.Person, error)
+	MergePatch(ctx context.Context, id int64, patch map[string]interface{}) (*models.Person, error)
+	JSONPatch(ctx context.Context, id int64, ops []models.PatchOperation) (*models.Person, error)
+	SelectAll(ctx context.Context, params models.Params) ([]models.Person, int64, error)
+	SelectByKeyset(ctx context.Context, params models.Params) ([]models.Person, error)
+	Search(ctx context.Context, q string, limit int) ([]models.SearchResult, error)
 	DeleteByID(ctx context.Context, id int64) error
 }
 
+type EnricherStats interface {
+	Health() []models.ProviderHealth
+	CacheStats() []models.CacheStats
+	Quotas() []models.QuotaStatus
+}
+
 type Router struct {
-	service PeopleService
+	service      PeopleService
+	stats        EnricherStats
+	reenrichRate float64
+	pageSize     int
+	maxPageSize  int
 }
 
-// New returns a new instance of Router.
-func New(service PeopleService) *Router {
+// New returns a new instance of Router, reenrichRate is the default rate of re-enrichment runs,
+// pageSize is the default and maxPageSize the max number of persons in a page.
+func New(service PeopleService, stats EnricherStats, reenrichRate float64, pageSize, maxPageSize int) *Router {
 	return &Router{
-		service: service,
+		service:      service,
+		stats:        stats,
+		reenrichRate: reenrichRate,
+		pageSize:     pageSize,
+		maxPageSize:  maxPageSize,
 	}
 }
 
@@ -49,12 +106,14 @@ func New(service PeopleService) *Router {
 //
 //	@Summary		Add Person
 //	@Tags			person
-//	@Description	get NSP to enrich it and add
+//	@Description	get NSP to enrich it and add, in async mode the person is saved at once and enriched by a job
 //	@ID				add-person
 //	@Accept			json
 //	@Produce		json
-//	@Param			RawPerson	body		models.RawPerson	true	"name, surname, patronymic"
+//	@Param			RawPerson	body		models.RawPerson	true	"name, surname, patronymic, optional country hint"
+//	@Param			async		query		bool				false	"enrich asynchronously, defaults to the service mode"
 //	@Success		201			{object}	response.Response
+//	@Success		202			{object}	response.Response{data=models.Job}
 //	@Failure		400			{object}	response.Response
 //	@Router			/person [post]
 func (r *Router) AddPerson(c echo.Context) error {
@@ -64,6 +123,30 @@ func (r *Router) AddPerson(c echo.Context) error {
 		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
 	}
 
+	if !validCountry(req.Country) {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidCountry.Error())
+	}
+
+	async := r.service.Async()
+	if a := c.QueryParam("async"); a != "" {
+		var err error
+		if async, err = strconv.ParseBool(a); err != nil {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+		}
+	}
+
+	if async {
+		job, err := r.service.EnqueuePerson(c.Request().Context(), req)
+		if err != nil {
+			if errors.Is(err, services.ErrAsyncDisabled) {
+				return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err.Error())
+			}
+			return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
+		}
+
+		return response.SendResponse(c, http.StatusAccepted, StatusAccepted, job)
+	}
+
 	id, err := r.service.AddPerson(c.Request().Context(), req)
 
 	if err != nil {
@@ -75,6 +158,114 @@ func (r *Router) AddPerson(c echo.Context) error {
 	})
 }
 
+// AddPersons godoc
+//
+//	@Summary		Add a bulk of persons
+//	@Tags			person
+//	@Description	get a list of NSP to enrich and add them, results are in the order of the list
+//	@ID				add-persons
+//	@Accept			json
+//	@Produce		json
+//	@Param			RawPersons	body		[]models.RawPerson	true	"list of name, surname, patronymic"
+//	@Success		201			{object}	response.Response{data=[]response.BulkItemResponse}
+//	@Failure		400			{object}	response.Response
+//	@Router			/persons [post]
+func (r *Router) AddPersons(c echo.Context) error {
+	var req []models.RawPerson
+
+	if err := c.Bind(&req); err != nil {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+	}
+
+	if len(req) == 0 || len(req) > MaxBulkSize {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrBulkSize.Error())
+	}
+
+	for _, p := range req {
+		if !validCountry(p.Country) {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidCountry.Error())
+		}
+	}
+
+	ids, errs := r.service.AddPersons(c.Request().Context(), req)
+
+	items := make([]response.BulkItemResponse, len(ids))
+	for i := range ids {
+		if errs[i] != nil {
+			items[i].Error = errs[i].Error()
+			continue
+		}
+		items[i].ID = ids[i]
+	}
+
+	return response.SendResponse(c, http.StatusCreated, StatusCrated, items)
+}
+
+// Enrich godoc
+//
+//	@Summary		Preview enrichment
+//	@Tags			person
+//	@Description	enrich NSP or a list of them without saving, results of a list are in its order
+//	@ID				enrich
+//	@Accept			json
+//	@Produce		json
+//	@Param			RawPerson	body		models.RawPerson	true	"name, surname, patronymic, optional country hint, or a list of them"
+//	@Success		200			{object}	response.Response{data=models.Person}	"person, or []response.EnrichItemResponse for a list"
+//	@Failure		400			{object}	response.Response
+//	@Router			/enrich [post]
+func (r *Router) Enrich(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		req := models.RawPerson{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err.Error())
+		}
+
+		if !validCountry(req.Country) {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidCountry.Error())
+		}
+
+		person, err := r.service.Enrich(c.Request().Context(), req)
+		if err != nil {
+			return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
+		}
+
+		return response.SendResponse(c, http.StatusOK, StatusOk, person)
+	}
+
+	var req []models.RawPerson
+	if err := json.Unmarshal(body, &req); err != nil {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err.Error())
+	}
+
+	if len(req) == 0 || len(req) > MaxBulkSize {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrBulkSize.Error())
+	}
+
+	for _, p := range req {
+		if !validCountry(p.Country) {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidCountry.Error())
+		}
+	}
+
+	persons, errs := r.service.EnrichAll(c.Request().Context(), req)
+
+	items := make([]response.EnrichItemResponse, len(persons))
+	for i := range persons {
+		if errs[i] != nil {
+			items[i].Error = errs[i].Error()
+			continue
+		}
+		items[i].Person = persons[i]
+	}
+
+	return response.SendResponse(c, http.StatusOK, StatusOk, items)
+}
+
 // SelectByID godoc
 //
 //	@Summary		Get person by id from storage
@@ -103,6 +294,64 @@ func (r *Router) SelectByID(c echo.Context) error {
 	return response.SendResponse(c, http.StatusOK, StatusOk, person)
 }
 
+// EnrichmentLog godoc
+//
+//	@Summary		Get enrichment log of person
+//	@Tags			person
+//	@Description	get provider calls made to enrich person with their raw responses
+//	@ID				get-person-enrichment
+//	@Produce		json
+//	@Param			id	path		int	true	"Person ID"
+//	@Success		200	{object}	response.Response{data=[]models.EnrichmentCall}
+//	@Failure		400	{object}	response.Response
+//	@Failure		404	{object}	response.Response
+//	@Router			/person/{id}/enrichment [get]
+func (r *Router) EnrichmentLog(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+	}
+
+	calls, err := r.service.SelectEnrichmentLog(c.Request().Context(), int64(id))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return response.SendResponse(c, http.StatusNotFound, StatusNotFound, err)
+		}
+		return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
+	}
+
+	return response.SendResponse(c, http.StatusOK, StatusOk, calls)
+}
+
+// SelectJob godoc
+//
+//	@Summary		Get enrichment job
+//	@Tags			person
+//	@Description	get state of async enrichment job and id of its person
+//	@ID				get-job
+//	@Produce		json
+//	@Param			id	path		int	true	"Job ID"
+//	@Success		200	{object}	response.Response{data=models.Job}
+//	@Failure		400	{object}	response.Response
+//	@Failure		404	{object}	response.Response
+//	@Router			/jobs/{id} [get]
+func (r *Router) SelectJob(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+	}
+
+	job, err := r.service.SelectJob(c.Request().Context(), int64(id))
+	if err != nil {
+		if errors.Is(err, storage.ErrJobNotFound) {
+			return response.SendResponse(c, http.StatusNotFound, StatusNotFound, err)
+		}
+		return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
+	}
+
+	return response.SendResponse(c, http.StatusOK, StatusOk, job)
+}
+
 // Update godoc
 //
 //	@Summary		Update person in storage
@@ -134,35 +383,238 @@ func (r *Router) Update(c echo.Context) error {
 	})
 }
 
+// Patch godoc
+//
+//	@Summary		Partially update person in storage
+//	@Tags			person
+//	@Description	Change name, surname, patronymic, age, gender or nationality of person by JSON Merge Patch (RFC 7396)
+//	@Description	or JSON Patch (RFC 6902) of its top-level fields, only changed fields are written.
+//	@Description	A null or removed field is cleared, name and surname can't be cleared.
+//	@Description	Age must not exceed 150, name and surname 100 characters, patronymic 200 characters.
+//	@ID				patch-person
+//	@Accept			application/merge-patch+json,application/json-patch+json,json
+//	@Produce		json
+//	@Param			id		path		int				true	"Person ID"
+//	@Param			input	body		object			true	"Merge patch object or array of JSON Patch operations"
+//	@Success		200		{object}	response.Response{data=models.Person}
+//	@Failure		400		{object}	response.Response
+//	@Failure		404		{object}	response.Response
+//	@Failure		409		{object}	response.Response	"JSON Patch test operation failed"
+//	@Failure		415		{object}	response.Response
+//	@Router			/person/{id} [patch]
+func (r *Router) Patch(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+	}
+
+	var person *models.Person
+
+	switch mediaType, _, _ := strings.Cut(c.Request().Header.Get(echo.HeaderContentType), ";"); strings.TrimSpace(mediaType) {
+	case MIMEJSONPatch:
+		var ops []models.PatchOperation
+		if err := json.NewDecoder(c.Request().Body).Decode(&ops); err != nil {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err.Error())
+		}
+
+		person, err = r.service.JSONPatch(c.Request().Context(), int64(id), ops)
+	case MIMEMergePatch, echo.MIMEApplicationJSON:
+		var patch map[string]interface{}
+		if err := json.NewDecoder(c.Request().Body).Decode(&patch); err != nil {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err.Error())
+		}
+
+		person, err = r.service.MergePatch(c.Request().Context(), int64(id), patch)
+	default:
+		return response.SendResponse(c, http.StatusUnsupportedMediaType, StatusUnsupported, ErrMediaType.Error())
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			return response.SendResponse(c, http.StatusNotFound, StatusNotFound, err)
+		case errors.Is(err, services.ErrInvalidPatch):
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrPatchTest):
+			return response.SendResponse(c, http.StatusConflict, StatusConflict, err.Error())
+		}
+		return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
+	}
+
+	return response.SendResponse(c, http.StatusOK, StatusOk, person)
+}
+
 // SelectAll godoc
 //
 //	@Summary		Get a list of persons by params
 //	@Tags			person
-//	@Description	Get a list of persons based on query parameters
+//	@Description	Get a list of persons based on query parameters. Pages are selected by page number,
+//	@Description	or by cursor if cursor parameter is set: pass an empty cursor to get the first page,
+//	@Description	then next_cursor or prev_cursor of the response along with the same filters and sort.
+//	@Description	Cursor pagination sorts by a single field of id, name, surname, age or enriched_at.
 //	@ID				get-all-persons
 //	@Accept			json
 //	@Produce		json
-//	@Param			page	query		int	true	"Id of page of results"
-//	@Success		200		{object}	response.Response
-//	@Failure		400		{object}	response.Response
+//	@Param			page				query		int		false	"Number of page of results"	default(1)
+//	@Param			cursor				query		string	false	"Cursor of page, switches to cursor pagination"
+//	@Param			page_size			query		int		false	"Number of persons in page, limited by configured maximum"
+//	@Param			sort				query		string	false	"Comma-separated fields to sort by, prefixed with - for descending order, e.g. age,-surname"
+//	@Param			name				query		string	false	"Name, case-insensitive"
+//	@Param			name_match			query		string	false	"Match of name"	Enums(exact, prefix, contains)	default(exact)
+//	@Param			surname				query		string	false	"Surname, case-insensitive"
+//	@Param			surname_match		query		string	false	"Match of surname"	Enums(exact, prefix, contains)	default(exact)
+//	@Param			patronymic			query		string	false	"Patronymic, case-insensitive"
+//	@Param			patronymic_match	query		string	false	"Match of patronymic"	Enums(exact, prefix, contains)	default(exact)
+//	@Param			age_min				query		int		false	"Min age, inclusive"
+//	@Param			age_max				query		int		false	"Max age, inclusive"
+//	@Param			gender				query		string	false	"Comma-separated genders, e.g. male,female"
+//	@Param			nationality			query		string	false	"Comma-separated country IDs of nationality, e.g. RU,UA"
+//	@Param			country				query		string	false	"Country ID from nationality distribution"
+//	@Param			country_probability	query		number	false	"Min probability of country"
+//	@Success		200					{object}	response.Response{data=response.PersonsResponse}	"Page by number, or response.CursorResponse by cursor"
+//	@Failure		400					{object}	response.Response
 //	@Router			/persons [get]
 func (r *Router) SelectAll(c echo.Context) error {
-	pageId, err := strconv.Atoi(c.QueryParam("page"))
+	page, err := queryInt(c, "page", 1)
+	if err != nil || page < 1 {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidPage.Error())
+	}
+
+	pageSize, err := queryInt(c, "page_size", r.pageSize)
+	if err != nil || pageSize < 1 || pageSize > r.maxPageSize {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidPageSize.Error())
+	}
+
+	sort, err := parseSort(c.QueryParam("sort"))
 	if err != nil {
-		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err.Error())
 	}
 
 	params := models.Params{
-		Limit:  PageSize,
-		Offset: (pageId - 1) * PageSize,
+		Limit:   pageSize,
+		Offset:  (page - 1) * pageSize,
+		Country: strings.ToUpper(c.QueryParam("country")),
+		Sort:    sort,
+	}
+
+	if p := c.QueryParam("country_probability"); p != "" {
+		params.CountryProbability, err = strconv.ParseFloat(p, 64)
+		if err != nil {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+		}
+		if params.CountryProbability < 0 || params.CountryProbability > 1 {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidProbability.Error())
+		}
+	}
+
+	if err := parseFilters(c, &params); err != nil {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err.Error())
+	}
+
+	if cur, ok := c.QueryParams()["cursor"]; ok {
+		if err := checkCursorSort(params.Sort); err != nil {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err.Error())
+		}
+
+		return r.selectByCursor(c, params, cur[0])
+	}
+
+	persons, total, err := r.service.SelectAll(c.Request().Context(), params)
+	if err != nil {
+		return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
 	}
 
-	persons, err := r.service.SelectAll(c.Request().Context(), params)
+	return response.SendResponse(c, http.StatusOK, StatusOk, response.PersonsResponse{
+		Persons:   persons,
+		Total:     total,
+		Page:      page,
+		PageSize:  pageSize,
+		PageCount: (total + int64(pageSize) - 1) / int64(pageSize),
+	})
+}
+
+// Search godoc
+//
+//	@Summary		Search persons by name
+//	@Tags			person
+//	@Description	Fuzzy search of persons by name, surname and patronymic, the most similar first
+//	@ID				search-persons
+//	@Accept			json
+//	@Produce		json
+//	@Param			q			query		string	true	"Name, surname, patronymic or a part of full name, e.g. Ivanof"
+//	@Param			page_size	query		int		false	"Max number of persons, limited by configured maximum"
+//	@Success		200			{object}	response.Response{data=[]models.SearchResult}
+//	@Failure		400			{object}	response.Response
+//	@Router			/persons/search [get]
+func (r *Router) Search(c echo.Context) error {
+	q := strings.Join(strings.Fields(c.QueryParam("q")), " ")
+	if q == "" {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrEmptyQuery.Error())
+	}
+
+	limit, err := queryInt(c, "page_size", r.pageSize)
+	if err != nil || limit < 1 || limit > r.maxPageSize {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidPageSize.Error())
+	}
+
+	results, err := r.service.Search(c.Request().Context(), q, limit)
 	if err != nil {
 		return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
 	}
 
-	return response.SendResponse(c, http.StatusOK, StatusOk, persons)
+	return response.SendResponse(c, http.StatusOK, StatusOk, results)
+}
+
+// selectByCursor sends page of persons after or before the position of cursor, the first page if cursor is empty.
+func (r *Router) selectByCursor(c echo.Context, params models.Params, cur string) error {
+	pageSize := params.Limit
+
+	if cur != "" {
+		keyset, err := decodeCursor(params, cur)
+		if err != nil {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err.Error())
+		}
+		params.After = keyset
+	}
+
+	// one more person tells whether there is a page after this one.
+	params.Limit = pageSize + 1
+	params.Offset = 0
+
+	persons, err := r.service.SelectByKeyset(c.Request().Context(), params)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidKeyset) {
+			return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidCursor.Error())
+		}
+		return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
+	}
+
+	backward := params.After != nil && params.After.Backward
+	more := len(persons) > pageSize
+
+	if more {
+		if backward {
+			persons = persons[1:]
+		} else {
+			persons = persons[:pageSize]
+		}
+	}
+
+	resp := response.CursorResponse{
+		Persons:  persons,
+		PageSize: pageSize,
+	}
+
+	if len(persons) > 0 {
+		if more || backward {
+			resp.NextCursor = encodeCursor(params, &persons[len(persons)-1], false)
+		}
+		if (more && backward) || (!backward && params.After != nil) {
+			resp.PrevCursor = encodeCursor(params, &persons[0], true)
+		}
+	}
+
+	return response.SendResponse(c, http.StatusOK, StatusOk, resp)
 }
 
 // DeleteByID godoc
@@ -190,6 +642,153 @@ func (r *Router) DeleteByID(c echo.Context) error {
 	return response.SendResponse(c, http.StatusNoContent, StatusNoContent, nil)
 }
 
+// Health godoc
+//
+//	@Summary		Get enrichment providers health
+//	@Tags			admin
+//	@Description	get circuit breaker state of every enrichment provider
+//	@ID				health
+//	@Produce		json
+//	@Success		200	{object}	response.Response{data=[]models.ProviderHealth}
+//	@Router			/health [get]
+func (r *Router) Health(c echo.Context) error {
+	return response.SendResponse(c, http.StatusOK, StatusOk, r.stats.Health())
+}
+
+// CacheStats godoc
+//
+//	@Summary		Get enrichment cache stats
+//	@Tags			admin
+//	@Description	get cache hit and miss counters of every enrichment provider
+//	@ID				cache-stats
+//	@Produce		json
+//	@Success		200	{object}	response.Response{data=[]models.CacheStats}
+//	@Router			/cache/stats [get]
+func (r *Router) CacheStats(c echo.Context) error {
+	return response.SendResponse(c, http.StatusOK, StatusOk, r.stats.CacheStats())
+}
+
+// Quotas godoc
+//
+//	@Summary		Get enrichment API quotas
+//	@Tags			admin
+//	@Description	get remaining request quota of every enrichment provider
+//	@ID				quotas
+//	@Produce		json
+//	@Success		200	{object}	response.Response{data=[]models.QuotaStatus}
+//	@Router			/quota [get]
+func (r *Router) Quotas(c echo.Context) error {
+	return response.SendResponse(c, http.StatusOK, StatusOk, r.stats.Quotas())
+}
+
+// StartReenrich godoc
+//
+//	@Summary		Start re-enrichment
+//	@Tags			admin
+//	@Description	re-enrich persons enriched before the time, by the provider or with low gender or nationality probability
+//	@ID				start-reenrich
+//	@Accept			json
+//	@Produce		json
+//	@Param			params	body		models.ReenrichParams	true	"filter and rate in persons per second"
+//	@Success		202		{object}	response.Response{data=models.ReenrichRun}
+//	@Failure		400		{object}	response.Response
+//	@Failure		503		{object}	response.Response
+//	@Router			/reenrich [post]
+func (r *Router) StartReenrich(c echo.Context) error {
+	req := models.ReenrichParams{}
+
+	if err := c.Bind(&req); err != nil {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+	}
+
+	if req.MaxProbability < 0 || req.MaxProbability > 1 {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidProbability.Error())
+	}
+
+	if req.Rate < 0 {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, ErrInvalidRate.Error())
+	}
+
+	if req.Rate == 0 {
+		req.Rate = r.reenrichRate
+	}
+
+	run, err := r.service.StartReenrich(c.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, services.ErrReenrichBusy) {
+			return response.SendResponse(c, http.StatusServiceUnavailable, StatusUnavailable, err.Error())
+		}
+		return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
+	}
+
+	return response.SendResponse(c, http.StatusAccepted, StatusAccepted, run)
+}
+
+// SelectReenrichRun godoc
+//
+//	@Summary		Get re-enrichment run
+//	@Tags			admin
+//	@Description	get state and progress of re-enrichment run with numbers of changed values
+//	@ID				get-reenrich
+//	@Produce		json
+//	@Param			id	path		int	true	"Run ID"
+//	@Success		200	{object}	response.Response{data=models.ReenrichRun}
+//	@Failure		400	{object}	response.Response
+//	@Failure		404	{object}	response.Response
+//	@Router			/reenrich/{id} [get]
+func (r *Router) SelectReenrichRun(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+	}
+
+	run, err := r.service.SelectReenrichRun(c.Request().Context(), int64(id))
+	if err != nil {
+		if errors.Is(err, storage.ErrRunNotFound) {
+			return response.SendResponse(c, http.StatusNotFound, StatusNotFound, err)
+		}
+		return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
+	}
+
+	return response.SendResponse(c, http.StatusOK, StatusOk, run)
+}
+
+// ResumeReenrich godoc
+//
+//	@Summary		Resume re-enrichment run
+//	@Tags			admin
+//	@Description	continue stopped or failed re-enrichment run from its last person
+//	@ID				resume-reenrich
+//	@Produce		json
+//	@Param			id	path		int	true	"Run ID"
+//	@Success		202	{object}	response.Response{data=models.ReenrichRun}
+//	@Failure		400	{object}	response.Response
+//	@Failure		404	{object}	response.Response
+//	@Failure		409	{object}	response.Response
+//	@Failure		503	{object}	response.Response
+//	@Router			/reenrich/{id}/resume [post]
+func (r *Router) ResumeReenrich(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return response.SendResponse(c, http.StatusBadRequest, StatusBadRequest, err)
+	}
+
+	run, err := r.service.ResumeReenrich(c.Request().Context(), int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrRunNotFound):
+			return response.SendResponse(c, http.StatusNotFound, StatusNotFound, err)
+		case errors.Is(err, services.ErrRunFinished), errors.Is(err, services.ErrRunActive):
+			return response.SendResponse(c, http.StatusConflict, StatusConflict, err.Error())
+		case errors.Is(err, services.ErrReenrichBusy):
+			return response.SendResponse(c, http.StatusServiceUnavailable, StatusUnavailable, err.Error())
+		}
+		return response.SendResponse(c, http.StatusInternalServerError, StatusInternal, err)
+	}
+
+	return response.SendResponse(c, http.StatusAccepted, StatusAccepted, run)
+}
+
 // Route setup router.
 func (r *Router) Route(e *echo.Echo) {
 	e.Use(middleware.Recover())
@@ -197,8 +796,138 @@ func (r *Router) Route(e *echo.Echo) {
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
 	e.POST("/person", r.AddPerson)
+	e.POST("/persons", r.AddPersons)
+	e.POST("/enrich", r.Enrich)
 	e.GET("/persons", r.SelectAll)
+	e.GET("/persons/search", r.Search)
 	e.GET("/person/:id", r.SelectByID)
+	e.GET("/person/:id/enrichment", r.EnrichmentLog)
 	e.DELETE("/person/:id", r.DeleteByID)
 	e.PUT("/person", r.Update)
+	e.PATCH("/person/:id", r.Patch)
+	e.GET("/jobs/:id", r.SelectJob)
+
+	e.GET("/health", r.Health)
+	e.GET("/cache/stats", r.CacheStats)
+	e.GET("/quota", r.Quotas)
+	e.POST("/reenrich", r.StartReenrich)
+	e.GET("/reenrich/:id", r.SelectReenrichRun)
+	e.POST("/reenrich/:id/resume", r.ResumeReenrich)
+}
+
+// parseFilters sets person filters of params from query parameters.
+func parseFilters(c echo.Context, params *models.Params) error {
+	for _, f := range []struct {
+		field  string
+		filter *models.TextFilter
+	}{
+		{"name", &params.Name},
+		{"surname", &params.Surname},
+		{"patronymic", &params.Patronymic},
+	} {
+		f.filter.Value = strings.TrimSpace(c.QueryParam(f.field))
+		f.filter.Match = c.QueryParam(f.field + "_match")
+
+		switch f.filter.Match {
+		case "":
+			f.filter.Match = models.MatchExact
+		case models.MatchExact, models.MatchPrefix, models.MatchContains:
+		default:
+			return ErrInvalidMatch
+		}
+	}
+
+	for _, a := range []struct {
+		param string
+		age   **uint
+	}{
+		{"age_min", &params.AgeMin},
+		{"age_max", &params.AgeMax},
+	} {
+		if v := c.QueryParam(a.param); v != "" {
+			age, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return ErrInvalidAge
+			}
+			n := uint(age)
+			*a.age = &n
+		}
+	}
+
+	if params.AgeMin != nil && params.AgeMax != nil && *params.AgeMin > *params.AgeMax {
+		return ErrInvalidAge
+	}
+
+	for _, g := range queryList(c, "gender") {
+		g = strings.ToLower(g)
+		if g != "male" && g != "female" {
+			return ErrInvalidGender
+		}
+		params.Genders = append(params.Genders, g)
+	}
+
+	for _, n := range queryList(c, "nationality") {
+		if !validCountry(n) {
+			return ErrInvalidCountry
+		}
+		params.Nationalities = append(params.Nationalities, strings.ToUpper(n))
+	}
+
+	if !validCountry(params.Country) {
+		return ErrInvalidCountry
+	}
+
+	return nil
+}
+
+// parseSort parses comma-separated sort fields, a field prefixed with - is sorted in descending order.
+func parseSort(sort string) ([]models.SortField, error) {
+	var fields []models.SortField
+
+	for _, item := range strings.Split(sort, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		field := models.SortField{Field: strings.TrimPrefix(item, "-")}
+		field.Desc = field.Field != item
+
+		if !slices.Contains(models.SortFields, field.Field) {
+			return nil, ErrInvalidSort
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// queryInt returns integer query parameter or defaultVal if it isn't set.
+func queryInt(c echo.Context, name string, defaultVal int) (int, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return defaultVal, nil
+	}
+
+	return strconv.Atoi(v)
+}
+
+// queryList returns non-empty values of comma-separated or repeated query parameter.
+func queryList(c echo.Context, name string) []string {
+	var list []string
+
+	for _, v := range c.QueryParams()[name] {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+
+	return list
+}
+
+// validCountry reports whether country is empty or a two-letter code.
+func validCountry(country string) bool {
+	return country == "" || models.IsCountryCode(country)
 }
@@ -0,0 +1,39 @@
+package enricher
+
+import (
+	"errors"
+	"github.com/binaryty/enricher-service/internal/models"
+)
+
+// CallError an error of a provider call which has been made and recorded.
+type CallError struct {
+	Call models.EnrichmentCall
+	Err  error
+}
+
+func (e *CallError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// appendCall appends provider call recorded in result or error to calls,
+// nothing is appended if no call has been made.
+func appendCall(calls []models.EnrichmentCall, res *Result, err error) []models.EnrichmentCall {
+	if err != nil {
+		var ce *CallError
+		if errors.As(err, &ce) {
+			return append(calls, ce.Call)
+		}
+
+		return calls
+	}
+
+	if res != nil && res.Call != nil {
+		return append(calls, *res.Call)
+	}
+
+	return calls
+}
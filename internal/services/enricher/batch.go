@@ -0,0 +1,147 @@
+package enricher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxBatchSize the max number of names accepted by the public APIs in a single request.
+const MaxBatchSize = 10
+
+// BatchResult an enrichment result of a single name in a batch.
+type BatchResult struct {
+	Result *Result
+	Err    error
+}
+
+// BatchProvider a provider able to enrich several names by a single call.
+type BatchProvider interface {
+	Provider
+	EnrichBatch(ctx context.Context, countryID string, names []string) ([]BatchResult, error)
+}
+
+// batchCall a caller waiting for its name to be enriched in a batch.
+type batchCall struct {
+	name string
+	res  BatchResult
+	done chan struct{}
+}
+
+// batch names of a single country collected for a single request.
+type batch struct {
+	countryID string
+	calls     []*batchCall
+	timer     *time.Timer
+}
+
+// batchingProvider collects names of concurrent calls over a short window and enriches them by batches.
+type batchingProvider struct {
+	BatchProvider
+	size    int
+	window  time.Duration
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*batch
+}
+
+// withBatching wraps provider with batching, a batch is sent once it has size names
+// or window has passed since its first name. Each batch request is limited by timeout.
+func withBatching(p BatchProvider, size int, window, timeout time.Duration) Provider {
+	if size <= 0 || size > MaxBatchSize {
+		size = MaxBatchSize
+	}
+
+	return &batchingProvider{
+		BatchProvider: p,
+		size:          size,
+		window:        window,
+		timeout:       timeout,
+		pending:       make(map[string]*batch),
+	}
+}
+
+// Enrich adds name to the current batch of its country and waits for its result.
+func (p *batchingProvider) Enrich(ctx context.Context, q Query) (*Result, error) {
+	call := &batchCall{
+		name: q.Name,
+		done: make(chan struct{}),
+	}
+
+	p.mu.Lock()
+
+	b, ok := p.pending[q.CountryID]
+	if !ok {
+		b = &batch{countryID: q.CountryID}
+		b.timer = time.AfterFunc(p.window, func() { p.flushPending(b) })
+		p.pending[q.CountryID] = b
+	}
+	b.calls = append(b.calls, call)
+
+	if len(b.calls) >= p.size {
+		p.take(b)
+		p.mu.Unlock()
+
+		go p.flush(b)
+	} else {
+		p.mu.Unlock()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.done:
+		return call.res.Result, call.res.Err
+	}
+}
+
+// take removes batch from pending ones, p.mu must be held.
+func (p *batchingProvider) take(b *batch) {
+	b.timer.Stop()
+
+	if p.pending[b.countryID] == b {
+		delete(p.pending, b.countryID)
+	}
+}
+
+// flushPending sends batch once its window has passed.
+func (p *batchingProvider) flushPending(b *batch) {
+	p.mu.Lock()
+	if p.pending[b.countryID] != b {
+		// the batch is full and has been sent already.
+		p.mu.Unlock()
+		return
+	}
+	p.take(b)
+	p.mu.Unlock()
+
+	p.flush(b)
+}
+
+// flush enriches batch names and hands results back to the callers.
+func (p *batchingProvider) flush(b *batch) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	names := make([]string, 0, len(b.calls))
+	for _, call := range b.calls {
+		names = append(names, call.name)
+	}
+
+	results, err := p.EnrichBatch(ctx, b.countryID, names)
+	if err == nil && len(results) != len(names) {
+		err = fmt.Errorf("%s: %w", p.Name(), ErrBatchMismatch)
+	}
+
+	for i, call := range b.calls {
+		if err != nil {
+			call.res = BatchResult{Err: err}
+		} else {
+			call.res = results[i]
+		}
+
+		close(call.done)
+	}
+}
@@ -0,0 +1,146 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBatchProvider a batch provider recording its batches.
+type fakeBatchProvider struct {
+	fakeProvider
+	mu      sync.Mutex
+	batches [][]string
+	batch   func(countryID string, names []string) ([]BatchResult, error)
+}
+
+func (p *fakeBatchProvider) EnrichBatch(_ context.Context, countryID string, names []string) ([]BatchResult, error) {
+	p.mu.Lock()
+	p.batches = append(p.batches, names)
+	p.mu.Unlock()
+
+	return p.batch(countryID, names)
+}
+
+// echoBatch returns results whose values are the names and countries.
+func echoBatch(countryID string, names []string) ([]BatchResult, error) {
+	results := make([]BatchResult, 0, len(names))
+	for _, name := range names {
+		results = append(results, BatchResult{Result: &Result{Value: countryID + name}})
+	}
+
+	return results, nil
+}
+
+func TestBatchingProvider(t *testing.T) {
+	errUpstream := errors.New("upstream failed")
+
+	tests := []struct {
+		name        string
+		size        int
+		window      time.Duration
+		queries     []Query
+		batch       func(string, []string) ([]BatchResult, error)
+		wantBatches int
+		wantErr     error
+	}{
+		{
+			name:        "flushed when full",
+			size:        3,
+			window:      time.Hour,
+			queries:     []Query{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			batch:       echoBatch,
+			wantBatches: 1,
+		},
+		{
+			name:        "flushed after window",
+			size:        10,
+			window:      10 * time.Millisecond,
+			queries:     []Query{{Name: "a"}, {Name: "b"}},
+			batch:       echoBatch,
+			wantBatches: 1,
+		},
+		{
+			name:        "batch per country",
+			size:        10,
+			window:      10 * time.Millisecond,
+			queries:     []Query{{Name: "a", CountryID: "RU"}, {Name: "b", CountryID: "UA"}, {Name: "c", CountryID: "RU"}},
+			batch:       echoBatch,
+			wantBatches: 2,
+		},
+		{
+			name:        "split by size",
+			size:        2,
+			window:      10 * time.Millisecond,
+			queries:     []Query{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			batch:       echoBatch,
+			wantBatches: 2,
+		},
+		{
+			name:    "error of batch for every name",
+			size:    2,
+			window:  time.Hour,
+			queries: []Query{{Name: "a"}, {Name: "b"}},
+			batch: func(string, []string) ([]BatchResult, error) {
+				return nil, errUpstream
+			},
+			wantBatches: 1,
+			wantErr:     errUpstream,
+		},
+		{
+			name:    "missing results",
+			size:    2,
+			window:  time.Hour,
+			queries: []Query{{Name: "a"}, {Name: "b"}},
+			batch: func(string, []string) ([]BatchResult, error) {
+				return []BatchResult{{Result: &Result{}}}, nil
+			},
+			wantBatches: 1,
+			wantErr:     ErrBatchMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeBatchProvider{
+				fakeProvider: fakeProvider{name: "fake", attr: AttributeAge},
+				batch:        tt.batch,
+			}
+			p := withBatching(fake, tt.size, tt.window, time.Second)
+
+			var wg sync.WaitGroup
+			for _, q := range tt.queries {
+				wg.Add(1)
+				go func(q Query) {
+					defer wg.Done()
+
+					ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+					defer cancel()
+
+					res, err := p.Enrich(ctx, q)
+					if tt.wantErr != nil {
+						if !errors.Is(err, tt.wantErr) {
+							t.Errorf("Enrich(%v) error = %v, want %v", q, err, tt.wantErr)
+						}
+						return
+					}
+					if err != nil {
+						t.Errorf("Enrich(%v) error = %v", q, err)
+						return
+					}
+					// a result must be handed to the caller of its name.
+					if res.Value != q.CountryID+q.Name {
+						t.Errorf("Enrich(%v) = %s, want %s", q, res.Value, q.CountryID+q.Name)
+					}
+				}(q)
+			}
+			wg.Wait()
+
+			if len(fake.batches) != tt.wantBatches {
+				t.Errorf("batches = %v, want %d batches", fake.batches, tt.wantBatches)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/models"
+	"sync"
+	"time"
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// Breaker a circuit breaker of a single provider.
+type Breaker struct {
+	mu        sync.Mutex
+	provider  string
+	attribute Attribute
+	threshold int
+	coolDown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+// NewBreaker returns a closed breaker which opens after threshold consecutive failures
+// and lets a single probe request through once coolDown has passed.
+// The breaker never opens if threshold <= 0.
+func NewBreaker(provider string, attribute Attribute, threshold int, coolDown time.Duration) *Breaker {
+	return &Breaker{
+		provider:  provider,
+		attribute: attribute,
+		threshold: threshold,
+		coolDown:  coolDown,
+		state:     StateClosed,
+	}
+}
+
+// Allow reports whether a request may be executed.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.coolDown {
+			return ErrBreakerOpen
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+	case StateHalfOpen:
+		if b.probing {
+			return ErrBreakerOpen
+		}
+		b.probing = true
+	}
+
+	return nil
+}
+
+// Success records a successful request and closes the breaker.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Release lets another probe through without changing breaker state.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// Failure records a failed request and opens the breaker if needed.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+
+	if b.state == StateHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Health returns current breaker state.
+func (b *Breaker) Health() models.ProviderHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := models.ProviderHealth{
+		Provider:  b.provider,
+		Attribute: string(b.attribute),
+		State:     b.state,
+		Failures:  b.failures,
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		h.OpenedAt = &openedAt
+	}
+
+	return h
+}
+
+// breakerProvider guards provider with a circuit breaker.
+type breakerProvider struct {
+	Provider
+	breaker *Breaker
+}
+
+// withBreaker wraps provider with breaker.
+func withBreaker(p Provider, b *Breaker) Provider {
+	return &breakerProvider{
+		Provider: p,
+		breaker:  b,
+	}
+}
+
+// Enrich fails fast while the breaker is open.
+func (p *breakerProvider) Enrich(ctx context.Context, q Query) (*Result, error) {
+	if err := p.breaker.Allow(); err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Name(), err)
+	}
+
+	res, err := p.Provider.Enrich(ctx, q)
+	switch {
+	case err == nil:
+		p.breaker.Success()
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrQuotaExhausted),
+		errors.Is(err, ErrNameNotFound), errors.Is(err, ErrNoCountry), errors.Is(err, ErrNoResult):
+		// the provider is fine, the failure says nothing about its health, unknown names included.
+		p.breaker.Release()
+	default:
+		p.breaker.Failure()
+	}
+
+	return res, err
+}
@@ -0,0 +1,101 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	const coolDown = 20 * time.Millisecond
+
+	// events: a allow (must succeed), d allow (must be denied), s success, f failure, r release, w wait cool down.
+	tests := []struct {
+		name      string
+		threshold int
+		events    string
+		want      string
+	}{
+		{name: "closed on success", threshold: 2, events: "asas", want: StateClosed},
+		{name: "closed below threshold", threshold: 3, events: "afafa", want: StateClosed},
+		{name: "success resets failures", threshold: 2, events: "afasafa", want: StateClosed},
+		{name: "opens at threshold", threshold: 2, events: "afafd", want: StateOpen},
+		{name: "never opens without threshold", threshold: 0, events: "afafafa", want: StateClosed},
+		{name: "half-open after cool down", threshold: 1, events: "afdwa", want: StateHalfOpen},
+		{name: "single probe in half-open", threshold: 1, events: "afwad", want: StateHalfOpen},
+		{name: "probe success closes", threshold: 1, events: "afwasa", want: StateClosed},
+		{name: "probe failure reopens", threshold: 1, events: "afwafd", want: StateOpen},
+		{name: "release lets next probe", threshold: 1, events: "afwara", want: StateHalfOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker("test", AttributeAge, tt.threshold, coolDown)
+
+			for i, e := range tt.events {
+				switch e {
+				case 'a':
+					if err := b.Allow(); err != nil {
+						t.Fatalf("event %d: Allow() = %v, want nil", i, err)
+					}
+				case 'd':
+					if err := b.Allow(); !errors.Is(err, ErrBreakerOpen) {
+						t.Fatalf("event %d: Allow() = %v, want ErrBreakerOpen", i, err)
+					}
+				case 's':
+					b.Success()
+				case 'f':
+					b.Failure()
+				case 'r':
+					b.Release()
+				case 'w':
+					time.Sleep(coolDown + 5*time.Millisecond)
+				}
+			}
+
+			if got := b.Health().State; got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoresUnknownNames(t *testing.T) {
+	const threshold = 3
+
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantState string
+	}{
+		{name: "unknown name", status: http.StatusOK, body: `{"count":0,"name":"zzz","country":[]}`, wantState: StateClosed},
+		{name: "server error", status: http.StatusInternalServerError, body: `{}`, wantState: StateOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			b := NewBreaker(ProviderNationalize, AttributeNationality, threshold, time.Minute)
+			p := withBreaker(NewNationalityProvider(srv.URL, 0, srv.Client()), b)
+
+			for i := 0; i < threshold; i++ {
+				if _, err := p.Enrich(context.Background(), Query{Name: "zzz"}); err == nil {
+					t.Fatalf("Enrich() error = nil, want error")
+				}
+			}
+
+			if got := b.Health().State; got != tt.wantState {
+				t.Errorf("state = %s, want %s", got, tt.wantState)
+			}
+		})
+	}
+}
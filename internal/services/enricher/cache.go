@@ -0,0 +1,81 @@
+package enricher
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/binaryty/enricher-service/internal/cache"
+	"github.com/binaryty/enricher-service/internal/models"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// CachedProvider serves provider results from cache.
+type CachedProvider struct {
+	Provider
+	cache  cache.Cache
+	ttl    time.Duration
+	log    *slog.Logger
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewCachedProvider wraps provider with cache, results are kept for ttl.
+func NewCachedProvider(p Provider, c cache.Cache, ttl time.Duration, log *slog.Logger) *CachedProvider {
+	return &CachedProvider{
+		Provider: p,
+		cache:    c,
+		ttl:      ttl,
+		log:      log,
+	}
+}
+
+// Enrich returns cached result or enriches query by provider and caches the result.
+func (p *CachedProvider) Enrich(ctx context.Context, q Query) (*Result, error) {
+	const op = "services.enricher.CachedProvider.Enrich"
+
+	key := p.Name() + ":" + q.key()
+
+	value, ok, err := p.cache.Get(ctx, key)
+	if err != nil {
+		p.log.Warn("can't get cached result", slog.String("operation", op), slog.String("[ERROR]", err.Error()))
+	}
+
+	if ok {
+		var res Result
+		if err := json.Unmarshal(value, &res); err == nil {
+			p.hits.Add(1)
+			if res.Call != nil {
+				res.Call.Cached = true
+			}
+
+			return &res, nil
+		}
+	}
+
+	p.misses.Add(1)
+
+	res, err := p.Provider.Enrich(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = json.Marshal(res)
+	if err == nil {
+		err = p.cache.Set(ctx, key, value, p.ttl)
+	}
+	if err != nil {
+		p.log.Warn("can't cache result", slog.String("operation", op), slog.String("[ERROR]", err.Error()))
+	}
+
+	return res, nil
+}
+
+// Stats returns cache hit and miss counters.
+func (p *CachedProvider) Stats() models.CacheStats {
+	return models.CacheStats{
+		Provider: p.Name(),
+		Hits:     p.hits.Load(),
+		Misses:   p.misses.Load(),
+	}
+}
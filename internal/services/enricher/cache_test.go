@@ -0,0 +1,107 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"github.com/binaryty/enricher-service/internal/cache"
+	"github.com/binaryty/enricher-service/internal/models"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestCachedProvider(t *testing.T) {
+	errProvider := errors.New("provider failed")
+
+	// queries are enriched in order, a query with err fails in the provider.
+	type query struct {
+		q          Query
+		err        error
+		wantCached bool
+	}
+
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		queries    []query
+		wantCalls  int
+		wantHits   int64
+		wantMisses int64
+	}{
+		{
+			name:       "miss then hit",
+			queries:    []query{{q: Query{Name: "anna"}}, {q: Query{Name: "anna"}, wantCached: true}},
+			wantCalls:  1,
+			wantHits:   1,
+			wantMisses: 1,
+		},
+		{
+			name:       "country is part of key",
+			queries:    []query{{q: Query{Name: "anna"}}, {q: Query{Name: "anna", CountryID: "RU"}}, {q: Query{Name: "anna", CountryID: "RU"}, wantCached: true}},
+			wantCalls:  2,
+			wantHits:   1,
+			wantMisses: 2,
+		},
+		{
+			name:       "errors aren't cached",
+			queries:    []query{{q: Query{Name: "anna"}, err: errProvider}, {q: Query{Name: "anna"}}, {q: Query{Name: "anna"}, wantCached: true}},
+			wantCalls:  2,
+			wantHits:   1,
+			wantMisses: 2,
+		},
+		{
+			name:       "expired result",
+			ttl:        time.Nanosecond,
+			queries:    []query{{q: Query{Name: "anna"}}, {q: Query{Name: "anna"}}},
+			wantCalls:  2,
+			wantMisses: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				calls int
+				fail  error
+			)
+			p := NewCachedProvider(&fakeProvider{
+				name: "p0",
+				attr: AttributeGender,
+				enrich: func(_ context.Context, q Query) (*Result, error) {
+					calls++
+					if fail != nil {
+						return nil, fail
+					}
+
+					return &Result{Provider: "p0", Value: "female", Call: &models.EnrichmentCall{Provider: "p0"}}, nil
+				},
+			}, cache.NewLRU(10), tt.ttl, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			for i, q := range tt.queries {
+				if tt.ttl > 0 {
+					time.Sleep(time.Millisecond)
+				}
+
+				fail = q.err
+				res, err := p.Enrich(context.Background(), q.q)
+				if !errors.Is(err, q.err) {
+					t.Fatalf("query %d: Enrich() error = %v, want %v", i, err, q.err)
+				}
+				if err != nil {
+					continue
+				}
+
+				if res.Value != "female" || res.Call.Cached != q.wantCached {
+					t.Errorf("query %d: Enrich() = %s cached %v, want female cached %v", i, res.Value, res.Call.Cached, q.wantCached)
+				}
+			}
+
+			stats := p.Stats()
+			if calls != tt.wantCalls || stats.Hits != tt.wantHits || stats.Misses != tt.wantMisses {
+				t.Errorf("calls %d, hits %d, misses %d, want %d, %d, %d",
+					calls, stats.Hits, stats.Misses, tt.wantCalls, tt.wantHits, tt.wantMisses)
+			}
+		})
+	}
+}
@@ -2,218 +2,439 @@ package enricher
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/binaryty/enricher-service/internal/cache"
 	"github.com/binaryty/enricher-service/internal/config"
 	"github.com/binaryty/enricher-service/internal/models"
-	"github.com/binaryty/enricher-service/internal/response"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
 	"net/http"
-	"sync"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 var (
 	ErrHandleAge         = errors.New("can't handle age")
 	ErrHandleGender      = errors.New("can't handle gender")
 	ErrHandleNationality = errors.New("can't handle nationality")
+	ErrUnknownAttribute  = errors.New("unknown attribute")
+	ErrNoProviders       = errors.New("no providers configured")
+	ErrUnknownMode       = errors.New("unknown enrichment mode")
+	ErrUnknownCache      = errors.New("unknown cache backend")
+	ErrNoResult          = errors.New("no result")
+)
+
+const (
+	ModeStrict  = "strict"
+	ModePartial = "partial"
+
+	CacheNone   = "none"
+	CacheMemory = "memory"
+	CacheRedis  = "redis"
 )
 
 type Enricher struct {
-	ageAPI         string
-	genderAPI      string
-	nationalityAPI string
-	client         *http.Client
+	log            *slog.Logger
+	partial        bool
+	twoPass        bool
+	strategies     map[Attribute]string
+	minProbability float64
+	weights        map[string]float64
+	normalizer     *Normalizer
+	providers      []Provider
+	breakers       []*Breaker
+	cached         []*CachedProvider
+	quotas         []*Quota
 }
 
-// New create a new instance of Enricher.
-func New(cfg *config.Config) *Enricher {
-	return &Enricher{
-		ageAPI:         cfg.API.Age,
-		genderAPI:      cfg.API.Gender,
-		nationalityAPI: cfg.API.Nationality,
-		client:         &http.Client{},
-	}
-}
+// New create a new instance of Enricher with providers listed in config.
+func New(cfg *config.Config, log *slog.Logger) (*Enricher, error) {
+	const op = "services.enricher.New"
 
-// Process processing enrich raw data.
-func (e *Enricher) Process(ctx context.Context, rawData models.RawPerson) (*models.Person, error) {
-	errCh := make(chan error)
-	resCh := make(chan *models.Person)
+	if cfg.Enricher.Mode != ModeStrict && cfg.Enricher.Mode != ModePartial {
+		return nil, fmt.Errorf("%s: %w: %s", op, ErrUnknownMode, cfg.Enricher.Mode)
+	}
 
-	handleCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	strategies := make(map[Attribute]string, 3)
+	for _, attr := range []Attribute{AttributeAge, AttributeGender, AttributeNationality} {
+		strategy := cfg.Enricher.Strategy.Default
+		if s, ok := cfg.Enricher.Strategy.Attributes[string(attr)]; ok {
+			strategy = s
+		}
 
-	go func() {
-		defer close(errCh)
-		defer close(resCh)
+		if strategy != StrategyFallback && strategy != StrategyConsensus {
+			return nil, fmt.Errorf("%s: %w: %s", op, ErrUnknownStrategy, strategy)
+		}
+		strategies[attr] = strategy
+	}
 
-		var (
-			wg              sync.WaitGroup
-			ageResp         *response.AgeResponse
-			genderResp      *response.GenderResponse
-			nationalityResp *response.NationalityResponse
-			err             error
-		)
+	normalizer, err := newNormalizer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-		wg.Add(3)
+	client := &http.Client{
+		Timeout: cfg.Enricher.CallTimeout,
+		Transport: newRetryTransport(http.DefaultTransport, RetryPolicy{
+			MaxAttempts:       cfg.Enricher.Retry.MaxAttempts,
+			BaseDelay:         cfg.Enricher.Retry.BaseDelay,
+			MaxDelay:          cfg.Enricher.Retry.MaxDelay,
+			Jitter:            cfg.Enricher.Retry.Jitter,
+			RetryableStatuses: cfg.Enricher.Retry.RetryableStatuses,
+		}, log),
+	}
 
-		go func() {
-			defer wg.Done()
+	c, err := newCache(cfg.Enricher.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-			ageResp, err = e.handleAge(handleCtx, rawData.Name)
-			if err != nil {
-				errCh <- err
-				return
-			}
-		}()
+	providers := make([]Provider, 0, len(cfg.Enricher.Providers))
+	breakers := make([]*Breaker, 0, len(cfg.Enricher.Providers))
+	cached := make([]*CachedProvider, 0, len(cfg.Enricher.Providers))
+	quotas := make([]*Quota, 0, len(cfg.Enricher.Providers))
+	for _, name := range cfg.Enricher.Providers {
+		p, err := newProvider(name, cfg, client)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
 
-		go func() {
-			defer wg.Done()
+		if qp, ok := p.(interface{ Quota() *Quota }); ok && qp.Quota() != nil {
+			quotas = append(quotas, qp.Quota())
+		}
 
-			genderResp, err = e.handleGender(handleCtx, rawData.Name)
-			if err != nil {
-				errCh <- err
-				return
-			}
-		}()
+		if bp, ok := p.(BatchProvider); ok && cfg.Enricher.Batch.Enabled {
+			p = withBatching(bp, cfg.Enricher.Batch.Size, cfg.Enricher.Batch.Window, cfg.Enricher.Batch.Timeout)
+		}
 
-		go func() {
-			defer wg.Done()
+		b := NewBreaker(p.Name(), p.Attribute(), cfg.Enricher.Breaker.FailureThreshold, cfg.Enricher.Breaker.CoolDown)
+		p = withBreaker(p, b)
+		breakers = append(breakers, b)
 
-			nationalityResp, err = e.handleNationality(handleCtx, rawData.Name)
-			if err != nil {
-				errCh <- err
-				return
-			}
-		}()
-
-		wg.Wait()
-		if ageResp != nil && genderResp != nil && nationalityResp != nil {
-			resCh <- &models.Person{
-				Name:        rawData.Name,
-				Surname:     rawData.Surname,
-				Patronymic:  rawData.Patronymic,
-				Age:         ageResp.Age,
-				Gender:      genderResp.Gender,
-				Nationality: nationalityResp.CountryID,
-			}
+		if c != nil {
+			cp := NewCachedProvider(p, c, cfg.Enricher.Cache.TTL, log)
+			p = cp
+			cached = append(cached, cp)
 		}
-	}()
 
-	select {
-	case <-ctx.Done():
-		return nil, fmt.Errorf("the timeout has expired")
-	case err := <-errCh:
-		return nil, err
-	case enrichData := <-resCh:
-		return enrichData, nil
+		providers = append(providers, withSharedCalls(p, cfg.Enricher.CallTimeout))
 	}
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoProviders)
+	}
+
+	e := NewWithProviders(log, providers,
+		WithMode(cfg.Enricher.Mode),
+		WithTwoPass(cfg.Enricher.TwoPass),
+		WithStrategies(strategies),
+		WithMinProbability(cfg.Enricher.Strategy.MinProbability),
+		WithWeights(cfg.Enricher.Strategy.Weights),
+		WithNormalizer(normalizer),
+	)
+	e.breakers = breakers
+	e.cached = cached
+	e.quotas = quotas
+
+	return e, nil
 }
 
-// handleAge get age from public API.
-func (e *Enricher) handleAge(ctx context.Context, name string) (*response.AgeResponse, error) {
-	const op = "services.enricher.handleAge"
+// Option configures Enricher.
+type Option func(e *Enricher)
 
-	uri := fmt.Sprintf("%s?name=%s", e.ageAPI, name)
+// WithMode sets enrichment mode, strict by default.
+func WithMode(mode string) Option {
+	return func(e *Enricher) {
+		e.partial = mode == ModePartial
+	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+// WithTwoPass makes Enricher resolve nationality first and localize
+// other attributes to it when the country isn't given.
+func WithTwoPass(twoPass bool) Option {
+	return func(e *Enricher) {
+		e.twoPass = twoPass
 	}
+}
 
-	resp, err := e.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+// WithStrategies sets strategies combining results of providers of the same attribute,
+// fallback by default.
+func WithStrategies(strategies map[Attribute]string) Option {
+	return func(e *Enricher) {
+		e.strategies = strategies
 	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ErrHandleAge
+// WithMinProbability sets probability below which fallback strategy asks the next provider.
+func WithMinProbability(p float64) Option {
+	return func(e *Enricher) {
+		e.minProbability = p
 	}
-	defer func() { _ = resp.Close }()
+}
 
-	ageResp := response.AgeResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&ageResp)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+// WithWeights sets provider weights used by consensus strategy, 1 by default.
+func WithWeights(weights map[string]float64) Option {
+	return func(e *Enricher) {
+		e.weights = weights
 	}
+}
 
-	return &ageResp, nil
+// WithNormalizer sets normalizer of names sent to providers,
+// names are only trimmed, case-folded and NFC-normalized by default.
+func WithNormalizer(n *Normalizer) Option {
+	return func(e *Enricher) {
+		e.normalizer = n
+	}
 }
 
-// handleGender get gender from public API.
-func (e *Enricher) handleGender(ctx context.Context, name string) (*response.GenderResponse, error) {
-	const op = "services.enricher.handleGender"
+// NewWithProviders create a new instance of Enricher with given providers.
+func NewWithProviders(log *slog.Logger, providers []Provider, opts ...Option) *Enricher {
+	e := &Enricher{
+		log:        log,
+		normalizer: NewNormalizer(false, nil),
+		providers:  providers,
+	}
 
-	uri := fmt.Sprintf("%s?name=%s", e.genderAPI, name)
+	for _, opt := range opts {
+		opt(e)
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	return e
+}
+
+// Process processing enrich raw data.
+func (e *Enricher) Process(ctx context.Context, rawData models.RawPerson) (*models.Person, error) {
+	return e.process(ctx, rawData, e.providers)
+}
+
+// ProcessAttributes processing enrich raw data with given attributes only.
+func (e *Enricher) ProcessAttributes(ctx context.Context, rawData models.RawPerson, attributes []string) (*models.Person, error) {
+	providers := make([]Provider, 0, len(e.providers))
+	for _, p := range e.providers {
+		for _, attr := range attributes {
+			if string(p.Attribute()) == attr {
+				providers = append(providers, p)
+				break
+			}
+		}
 	}
 
-	resp, err := e.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	return e.process(ctx, rawData, providers)
+}
+
+// outcome a result of a single attribute resolution.
+type outcome struct {
+	attribute Attribute
+	res       *Result
+	calls     []models.EnrichmentCall
+	err       error
+}
+
+// process fans out over providers and collects their results into person.
+func (e *Enricher) process(ctx context.Context, rawData models.RawPerson, providers []Provider) (*models.Person, error) {
+	person := &models.Person{
+		Name:             rawData.Name,
+		Surname:          rawData.Surname,
+		Patronymic:       rawData.Patronymic,
+		EnrichmentStatus: models.EnrichmentComplete,
 	}
+	enriched := make(map[Attribute]bool, len(providers))
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ErrHandleGender
+	// providers get the normalized name, the person keeps the original spelling.
+	q := Query{
+		Name:      e.normalizer.Normalize(rawData.Name),
+		CountryID: strings.ToUpper(strings.TrimSpace(rawData.Country)),
 	}
-	defer func() { _ = resp.Close }()
 
-	genderResp := response.GenderResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&genderResp)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	passes := [][]Provider{providers}
+	if e.twoPass && q.CountryID == "" {
+		passes = splitByAttribute(providers, AttributeNationality)
 	}
 
-	return &genderResp, nil
+	for i, pass := range passes {
+		if i > 0 && person.Nationality != "" {
+			q.CountryID = person.Nationality
+		}
+
+		if err := e.run(ctx, q, pass, person, enriched); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range providers {
+		attr := string(p.Attribute())
+		if enriched[p.Attribute()] || slices.Contains(person.PendingAttributes, attr) {
+			continue
+		}
+
+		person.PendingAttributes = append(person.PendingAttributes, attr)
+		person.EnrichmentStatus = models.EnrichmentPending
+	}
+
+	return person, nil
 }
 
-// handleNationality get nationality from public API.
-func (e *Enricher) handleNationality(ctx context.Context, name string) (*response.NationalityResponse, error) {
-	const op = "services.enricher.handleNationality"
+// run fans out query over attributes of providers and applies their results to person.
+func (e *Enricher) run(ctx context.Context, q Query, providers []Provider, person *models.Person, enriched map[Attribute]bool) error {
+	const op = "services.enricher.Process"
 
-	uri := fmt.Sprintf("%s?name=%s", e.nationalityAPI, name)
+	handleCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	attrs, groups := groupByAttribute(providers)
+	outCh := make(chan outcome, len(attrs))
+
+	for _, attr := range attrs {
+		go func(attr Attribute) {
+			res, calls, err := e.resolve(handleCtx, q, attr, groups[attr])
+			outCh <- outcome{attribute: attr, res: res, calls: calls, err: err}
+		}(attr)
 	}
 
-	resp, err := e.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	for range attrs {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the timeout has expired")
+		case out := <-outCh:
+			person.Calls = append(person.Calls, out.calls...)
+
+			if out.err != nil {
+				if !e.partial {
+					return out.err
+				}
+
+				e.log.Warn("can't enrich attribute",
+					slog.String("operation", op),
+					slog.String("attribute", string(out.attribute)),
+					slog.String("[ERROR]", out.err.Error()),
+				)
+				continue
+			}
+
+			if err := apply(person, out.res); err != nil {
+				return err
+			}
+			enriched[out.res.Attribute] = true
+		}
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, ErrHandleNationality
+	return nil
+}
+
+// groupByAttribute groups providers by attribute keeping their order,
+// attributes are returned in order of their first provider.
+func groupByAttribute(providers []Provider) ([]Attribute, map[Attribute][]Provider) {
+	var attrs []Attribute
+	groups := make(map[Attribute][]Provider)
+	for _, p := range providers {
+		if _, ok := groups[p.Attribute()]; !ok {
+			attrs = append(attrs, p.Attribute())
+		}
+		groups[p.Attribute()] = append(groups[p.Attribute()], p)
 	}
-	defer func() { _ = resp.Close }()
 
-	nationResp := response.NationalitysResponse{}
+	return attrs, groups
+}
 
-	err = json.NewDecoder(resp.Body).Decode(&nationResp)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+// splitByAttribute splits providers into those producing attr and the rest,
+// empty groups are omitted.
+func splitByAttribute(providers []Provider, attr Attribute) [][]Provider {
+	var first, rest []Provider
+	for _, p := range providers {
+		if p.Attribute() == attr {
+			first = append(first, p)
+		} else {
+			rest = append(rest, p)
+		}
+	}
+
+	passes := make([][]Provider, 0, 2)
+	for _, pass := range [][]Provider{first, rest} {
+		if len(pass) > 0 {
+			passes = append(passes, pass)
+		}
 	}
 
-	return getCountry(nationResp), nil
+	return passes
 }
 
-// getCountry ...
-func getCountry(countries response.NationalitysResponse) *response.NationalityResponse {
-	var maxProbability float32
-	var idx int
+// Health returns circuit breaker states of providers.
+func (e *Enricher) Health() []models.ProviderHealth {
+	health := make([]models.ProviderHealth, 0, len(e.breakers))
+	for _, b := range e.breakers {
+		health = append(health, b.Health())
+	}
+
+	return health
+}
 
-	c := countries.Countries
+// CacheStats returns cache hit and miss counters of providers.
+func (e *Enricher) CacheStats() []models.CacheStats {
+	stats := make([]models.CacheStats, 0, len(e.cached))
+	for _, cp := range e.cached {
+		stats = append(stats, cp.Stats())
+	}
 
-	for i := 0; i < len(c); i++ {
-		if c[i].Probability > maxProbability {
-			maxProbability = c[i].Probability
-			idx = i
+	return stats
+}
+
+// Quotas returns quota states of providers.
+func (e *Enricher) Quotas() []models.QuotaStatus {
+	quotas := make([]models.QuotaStatus, 0, len(e.quotas))
+	for _, q := range e.quotas {
+		quotas = append(quotas, q.Status())
+	}
+
+	return quotas
+}
+
+// apply set result value to the corresponding person attribute.
+func apply(person *models.Person, res *Result) error {
+	switch res.Attribute {
+	case AttributeAge:
+		age, err := strconv.ParseUint(res.Value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%s: %w", res.Provider, err)
 		}
+		person.Age = uint(age)
+		person.AgeCount = res.Count
+		person.AgeSource = strings.Join(res.Sources, ",")
+	case AttributeGender:
+		person.Gender = res.Value
+		person.GenderProbability = res.Probability
+		person.GenderCount = res.Count
+		person.GenderSource = strings.Join(res.Sources, ",")
+	case AttributeNationality:
+		person.Nationality = res.Value
+		person.NationalityProbability = res.Probability
+		person.NationalityCount = res.Count
+		person.NationalitySource = strings.Join(res.Sources, ",")
+		person.Countries = res.Countries
+	default:
+		return fmt.Errorf("%s: %w: %s", res.Provider, ErrUnknownAttribute, res.Attribute)
 	}
 
-	return &countries.Countries[idx]
+	return nil
+}
+
+// newCache creates cache backend from config, nil if caching is disabled.
+func newCache(cfg config.Cache) (cache.Cache, error) {
+	switch cfg.Backend {
+	case CacheNone, "":
+		return nil, nil
+	case CacheMemory:
+		return cache.NewLRU(cfg.Size), nil
+	case CacheRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPass,
+			DB:       cfg.RedisDB,
+		})
+
+		return cache.NewRedis(client, "enricher:"), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCache, cfg.Backend)
+	}
 }
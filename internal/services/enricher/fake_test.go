@@ -0,0 +1,22 @@
+package enricher
+
+import "context"
+
+// fakeProvider a provider answering by function.
+type fakeProvider struct {
+	name   string
+	attr   Attribute
+	enrich func(ctx context.Context, q Query) (*Result, error)
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Attribute() Attribute {
+	return p.attr
+}
+
+func (p *fakeProvider) Enrich(ctx context.Context, q Query) (*Result, error) {
+	return p.enrich(ctx, q)
+}
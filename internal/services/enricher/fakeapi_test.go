@@ -0,0 +1,257 @@
+package enricher_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/config"
+	"github.com/binaryty/enricher-service/internal/fakeapi"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/services/enricher"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// names of the fake APIs dataset and their age, gender and the most probable country.
+var names = map[string]struct {
+	age     uint
+	gender  string
+	country string
+}{
+	"anna":  {age: 30, gender: "female", country: "UA"},
+	"ivan":  {age: 41, gender: "male", country: "RU"},
+	"maria": {age: 35, gender: "female", country: "ES"},
+	"olga":  {age: 38, gender: "female", country: "RU"},
+	"piotr": {age: 44, gender: "male", country: "PL"},
+}
+
+// newFakeAPI starts fake APIs serving names with opts.
+func newFakeAPI(t *testing.T, opts fakeapi.Options) *fakeapi.Server {
+	t.Helper()
+
+	rows := "name,attribute,value,probability,count,country_id\n"
+	for name, n := range names {
+		rows += fmt.Sprintf("%s,age,%d,0,100,\n", name, n.age)
+		rows += fmt.Sprintf("%s,gender,%s,0.95,100,\n", name, n.gender)
+		rows += fmt.Sprintf("%s,nationality,%s,0.6,100,\n", name, n.country)
+	}
+
+	path := filepath.Join(t.TempDir(), "names.csv")
+	if err := os.WriteFile(path, []byte(rows), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := enricher.LoadDataset(path, enricher.NewNormalizer(false, nil))
+	if err != nil {
+		t.Fatalf("LoadDataset() error = %v", err)
+	}
+
+	srv := fakeapi.NewServer(ds, opts)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// newEnricher returns enricher with HTTP providers of srv configured by cfg.
+func newEnricher(t *testing.T, srv *fakeapi.Server, configure func(cfg *config.Config)) *enricher.Enricher {
+	t.Helper()
+
+	cfg := &config.Config{
+		API: config.API{
+			Age:         srv.AgeURL(),
+			Gender:      srv.GenderURL(),
+			Nationality: srv.NationalityURL(),
+		},
+		Enricher: config.Enricher{
+			Mode:         enricher.ModeStrict,
+			Providers:    []string{enricher.ProviderAgify, enricher.ProviderGenderize, enricher.ProviderNationalize},
+			TopCountries: 3,
+			CallTimeout:  5 * time.Second,
+			Retry:        config.Retry{MaxAttempts: 1},
+			Breaker:      config.Breaker{FailureThreshold: 3, CoolDown: time.Minute},
+			Cache:        config.Cache{Backend: enricher.CacheNone},
+			Strategy:     config.Strategy{Default: enricher.StrategyFallback},
+		},
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	e, err := enricher.New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	return e
+}
+
+// checkPerson checks person is enriched with attributes of name.
+func checkPerson(t *testing.T, name string, person *models.Person) {
+	t.Helper()
+
+	want := names[name]
+	if person.Age != want.age || person.Gender != want.gender || person.Nationality != want.country {
+		t.Errorf("%s enriched as %d %s %s, want %d %s %s", name,
+			person.Age, person.Gender, person.Nationality, want.age, want.gender, want.country)
+	}
+}
+
+// checkBreakers checks states of all breakers of e.
+func checkBreakers(t *testing.T, e *enricher.Enricher, want string) {
+	t.Helper()
+
+	for _, h := range e.Health() {
+		if h.State != want {
+			t.Errorf("breaker of %s is %s, want %s", h.Provider, h.State, want)
+		}
+	}
+}
+
+func TestFakeAPI(t *testing.T) {
+	srv := newFakeAPI(t, fakeapi.Options{Limit: 100, Window: time.Hour})
+	e := newEnricher(t, srv, nil)
+
+	for name := range names {
+		person, err := e.Process(context.Background(), models.RawPerson{Name: name, Surname: "Petrova"})
+		if err != nil {
+			t.Fatalf("Process(%s) error = %v", name, err)
+		}
+		checkPerson(t, name, person)
+	}
+
+	checkBreakers(t, e, enricher.StateClosed)
+
+	quotas := e.Quotas()
+	if len(quotas) != 3 {
+		t.Fatalf("quotas = %v, want 3", quotas)
+	}
+	// the fake APIs share the limit, so each provider sees names of the others too.
+	for _, q := range quotas {
+		if !q.Known || q.Limit != 100 || q.Remaining > 100-len(names) || q.Remaining < 100-3*len(names) {
+			t.Errorf("quota of %s = %+v, want %d to %d of 100 remaining", q.Provider, q, 100-3*len(names), 100-len(names))
+		}
+	}
+}
+
+func TestFakeAPIBatching(t *testing.T) {
+	srv := newFakeAPI(t, fakeapi.Options{Latency: 5 * time.Millisecond})
+	e := newEnricher(t, srv, func(cfg *config.Config) {
+		cfg.Enricher.Batch = config.Batch{Enabled: true, Size: 3, Window: 20 * time.Millisecond, Timeout: time.Second}
+	})
+
+	var wg sync.WaitGroup
+	for round := 0; round < 3; round++ {
+		for name := range names {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+
+				person, err := e.Process(context.Background(), models.RawPerson{Name: name, Surname: "Petrova"})
+				if err != nil {
+					t.Errorf("Process(%s) error = %v", name, err)
+					return
+				}
+				checkPerson(t, name, person)
+			}(name)
+		}
+	}
+	wg.Wait()
+
+	checkBreakers(t, e, enricher.StateClosed)
+}
+
+func TestFakeAPIRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries int
+		wantErr bool
+	}{
+		{name: "errors retried", retries: 20},
+		{name: "errors not retried", retries: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeAPI(t, fakeapi.Options{ErrorRate: 0.5, Seed: 7})
+			e := newEnricher(t, srv, func(cfg *config.Config) {
+				cfg.Enricher.Retry = config.Retry{
+					MaxAttempts:       tt.retries,
+					BaseDelay:         time.Millisecond,
+					MaxDelay:          time.Millisecond,
+					RetryableStatuses: []int{http.StatusInternalServerError},
+				}
+				cfg.Enricher.Breaker.FailureThreshold = 0
+			})
+
+			var failed int
+			for name := range names {
+				person, err := e.Process(context.Background(), models.RawPerson{Name: name, Surname: "Petrova"})
+				if err != nil {
+					failed++
+					continue
+				}
+				checkPerson(t, name, person)
+			}
+
+			if gotErr := failed > 0; gotErr != tt.wantErr {
+				t.Errorf("%d of %d persons failed, want failures %v", failed, len(names), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFakeAPIBreaker(t *testing.T) {
+	const threshold = 3
+
+	tests := []struct {
+		name          string
+		opts          fakeapi.Options
+		person        string
+		wantState     string
+		wantExhausted bool
+		wantCalls     int
+	}{
+		{name: "server errors open breakers", opts: fakeapi.Options{ErrorRate: 1}, person: "anna", wantState: enricher.StateOpen},
+		{name: "used up limit exhausts quotas", opts: fakeapi.Options{Limit: 1}, person: "anna", wantState: enricher.StateClosed, wantExhausted: true},
+		{name: "unknown names keep breakers closed", person: "zzz", wantState: enricher.StateClosed, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeAPI(t, tt.opts)
+			// partial mode resolves every attribute, strict one stops at the first failure.
+			e := newEnricher(t, srv, func(cfg *config.Config) {
+				cfg.Enricher.Mode = enricher.ModePartial
+				cfg.Enricher.Breaker.FailureThreshold = threshold
+			})
+
+			for i := 0; i < threshold; i++ {
+				if _, err := e.Process(context.Background(), models.RawPerson{Name: tt.person, Surname: "Petrova"}); err != nil {
+					t.Fatalf("Process() error = %v", err)
+				}
+			}
+
+			checkBreakers(t, e, tt.wantState)
+
+			// refused calls don't reach the APIs.
+			person, err := e.Process(context.Background(), models.RawPerson{Name: tt.person, Surname: "Petrova"})
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if len(person.PendingAttributes) != 3 || len(person.Calls) != tt.wantCalls {
+				t.Errorf("pending attributes = %v after %d calls, want all after %d", person.PendingAttributes, len(person.Calls), tt.wantCalls)
+			}
+
+			for _, q := range e.Quotas() {
+				if q.Exhausted != tt.wantExhausted {
+					t.Errorf("quota of %s exhausted = %v, want %v", q.Provider, q.Exhausted, tt.wantExhausted)
+				}
+			}
+		})
+	}
+}
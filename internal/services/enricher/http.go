@@ -0,0 +1,382 @@
+package enricher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/config"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/response"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	ProviderAgify       = "agify"
+	ProviderGenderize   = "genderize"
+	ProviderNationalize = "nationalize"
+)
+
+// paramAPIKey query parameter of API key, it must never be logged or recorded.
+const paramAPIKey = "apikey"
+
+var (
+	ErrNoCountry     = errors.New("no country in response")
+	ErrBatchMismatch = errors.New("batch response doesn't match request")
+)
+
+func init() {
+	Register(ProviderAgify, func(cfg *config.Config, client *http.Client) (Provider, error) {
+		return NewAgeProvider(cfg.API.Age, client,
+			WithAPIKey(cfg.API.AgeKey),
+			WithQuota(NewQuota(ProviderAgify, cfg.Enricher.Quota.Floor, cfg.Enricher.Quota.Queue)),
+		), nil
+	})
+	Register(ProviderGenderize, func(cfg *config.Config, client *http.Client) (Provider, error) {
+		return NewGenderProvider(cfg.API.Gender, client,
+			WithAPIKey(cfg.API.GenderKey),
+			WithQuota(NewQuota(ProviderGenderize, cfg.Enricher.Quota.Floor, cfg.Enricher.Quota.Queue)),
+		), nil
+	})
+	Register(ProviderNationalize, func(cfg *config.Config, client *http.Client) (Provider, error) {
+		return NewNationalityProvider(cfg.API.Nationality, cfg.Enricher.TopCountries, client,
+			WithAPIKey(cfg.API.NationalityKey),
+			WithQuota(NewQuota(ProviderNationalize, cfg.Enricher.Quota.Floor, cfg.Enricher.Quota.Queue)),
+		), nil
+	})
+}
+
+// HTTPProvider a provider backed by one of the public name APIs.
+type HTTPProvider struct {
+	name      string
+	attribute Attribute
+	localized bool
+	api       string
+	apiKey    string
+	quota     *Quota
+	client    *http.Client
+	errStatus error
+	decode    func(r io.Reader) (*Result, error)
+}
+
+// HTTPOption configures HTTPProvider.
+type HTTPOption func(p *HTTPProvider)
+
+// WithAPIKey sets API key sent with every request.
+func WithAPIKey(key string) HTTPOption {
+	return func(p *HTTPProvider) {
+		p.apiKey = key
+	}
+}
+
+// WithQuota sets quota tracker of provider.
+func WithQuota(q *Quota) HTTPOption {
+	return func(p *HTTPProvider) {
+		p.quota = q
+	}
+}
+
+// NewAgeProvider returns a provider of age backed by agify API.
+func NewAgeProvider(api string, client *http.Client, opts ...HTTPOption) *HTTPProvider {
+	return newHTTPProvider(&HTTPProvider{
+		name:      ProviderAgify,
+		attribute: AttributeAge,
+		localized: true,
+		api:       api,
+		client:    client,
+		errStatus: ErrHandleAge,
+		decode:    decodeAge,
+	}, opts)
+}
+
+// NewGenderProvider returns a provider of gender backed by genderize API.
+func NewGenderProvider(api string, client *http.Client, opts ...HTTPOption) *HTTPProvider {
+	return newHTTPProvider(&HTTPProvider{
+		name:      ProviderGenderize,
+		attribute: AttributeGender,
+		localized: true,
+		api:       api,
+		client:    client,
+		errStatus: ErrHandleGender,
+		decode:    decodeGender,
+	}, opts)
+}
+
+// NewNationalityProvider returns a provider of nationality backed by nationalize API.
+// The result keeps at most topN most probable countries, all of them if topN <= 0.
+func NewNationalityProvider(api string, topN int, client *http.Client, opts ...HTTPOption) *HTTPProvider {
+	return newHTTPProvider(&HTTPProvider{
+		name:      ProviderNationalize,
+		attribute: AttributeNationality,
+		api:       api,
+		client:    client,
+		errStatus: ErrHandleNationality,
+		decode:    decodeNationality(topN),
+	}, opts)
+}
+
+// newHTTPProvider applies options to provider.
+func newHTTPProvider(p *HTTPProvider, opts []HTTPOption) *HTTPProvider {
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Name returns provider name.
+func (p *HTTPProvider) Name() string {
+	return p.name
+}
+
+// Attribute returns attribute produced by provider.
+func (p *HTTPProvider) Attribute() Attribute {
+	return p.attribute
+}
+
+// Quota returns quota tracker of provider, nil if quota isn't tracked.
+func (p *HTTPProvider) Quota() *Quota {
+	return p.quota
+}
+
+// Enrich get attribute value from public API.
+func (p *HTTPProvider) Enrich(ctx context.Context, q Query) (*Result, error) {
+	const op = "services.enricher.HTTPProvider.Enrich"
+
+	body, call, err := p.do(ctx, url.Values{"name": {q.Name}}, q.CountryID, 1)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := p.decode(bytes.NewReader(body))
+	if err != nil {
+		call.Error = err.Error()
+		return nil, fmt.Errorf("%s: %w", op, &CallError{Call: call, Err: err})
+	}
+	res.Provider = p.name
+	res.Attribute = p.attribute
+	res.Call = &call
+
+	return res, nil
+}
+
+// EnrichBatch get attribute values of several names localized to the same country
+// from public API by a single request.
+func (p *HTTPProvider) EnrichBatch(ctx context.Context, countryID string, names []string) ([]BatchResult, error) {
+	const op = "services.enricher.HTTPProvider.EnrichBatch"
+
+	body, call, err := p.do(ctx, url.Values{"name[]": names}, countryID, len(names))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		call.Error = err.Error()
+		return nil, fmt.Errorf("%s: %w", op, &CallError{Call: call, Err: err})
+	}
+
+	if len(items) != len(names) {
+		err := fmt.Errorf("%w: got %d results for %d names", ErrBatchMismatch, len(items), len(names))
+		call.Error = err.Error()
+		return nil, fmt.Errorf("%s: %w", op, &CallError{Call: call, Err: err})
+	}
+
+	results := make([]BatchResult, 0, len(items))
+	for _, item := range items {
+		// every name is recorded with its own part of the batch response.
+		itemCall := call
+		itemCall.Body = string(item)
+
+		res, err := p.decode(bytes.NewReader(item))
+		if err != nil {
+			itemCall.Error = err.Error()
+			results = append(results, BatchResult{Err: fmt.Errorf("%s: %w", op, &CallError{Call: itemCall, Err: err})})
+			continue
+		}
+		res.Provider = p.name
+		res.Attribute = p.attribute
+		res.Call = &itemCall
+
+		results = append(results, BatchResult{Result: res})
+	}
+
+	return results, nil
+}
+
+// do sends request for n names to public API and returns body of successful response
+// with the record of the call. Errors of the made calls are returned as CallError.
+// Country is ignored by providers which don't support localization.
+func (p *HTTPProvider) do(ctx context.Context, query url.Values, countryID string, n int) ([]byte, models.EnrichmentCall, error) {
+	call := models.EnrichmentCall{
+		Provider:  p.name,
+		Attribute: string(p.attribute),
+	}
+
+	if p.quota != nil {
+		if err := p.quota.Acquire(ctx, n); err != nil {
+			return nil, call, err
+		}
+	}
+
+	if p.localized && countryID != "" {
+		query.Set("country_id", countryID)
+	}
+
+	// the API key must not get into the recorded URL.
+	call.URL = fmt.Sprintf("%s?%s", p.api, query.Encode())
+
+	if p.apiKey != "" {
+		query.Set(paramAPIKey, p.apiKey)
+	}
+
+	uri := fmt.Sprintf("%s?%s", p.api, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, call, err
+	}
+
+	call.CreatedAt = time.Now()
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		// url.Error prints the request URL with the API key.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		call.LatencyMs = time.Since(call.CreatedAt).Milliseconds()
+		call.Error = err.Error()
+		return nil, call, &CallError{Call: call, Err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	call.LatencyMs = time.Since(call.CreatedAt).Milliseconds()
+	call.Status = resp.StatusCode
+	call.Body = string(body)
+
+	if p.quota != nil {
+		p.quota.Update(resp.Header)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		call.Error = p.errStatus.Error()
+		return nil, call, &CallError{Call: call, Err: p.errStatus}
+	}
+
+	if err != nil {
+		call.Error = err.Error()
+		return nil, call, &CallError{Call: call, Err: err}
+	}
+
+	return body, call, nil
+}
+
+// decodeAge decodes agify response, ErrNameNotFound is returned for unknown names.
+func decodeAge(r io.Reader) (*Result, error) {
+	ageResp := response.AgeResponse{}
+	if err := json.NewDecoder(r).Decode(&ageResp); err != nil {
+		return nil, err
+	}
+
+	// agify answers null age for unknown names, other providers may know them.
+	if ageResp.Age == nil || ageResp.Count == 0 {
+		return nil, ErrNameNotFound
+	}
+
+	return &Result{
+		Value: strconv.FormatUint(uint64(*ageResp.Age), 10),
+		Count: ageResp.Count,
+	}, nil
+}
+
+// decodeGender decodes genderize response.
+func decodeGender(r io.Reader) (*Result, error) {
+	genderResp := response.GenderResponse{}
+	if err := json.NewDecoder(r).Decode(&genderResp); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Value:       genderResp.Gender,
+		Probability: genderResp.Probability,
+		Count:       genderResp.Count,
+	}, nil
+}
+
+// decodeNationality returns decoder of nationalize response keeping topN countries.
+func decodeNationality(topN int) func(r io.Reader) (*Result, error) {
+	return func(r io.Reader) (*Result, error) {
+		nationResp := response.NationalitysResponse{}
+		if err := json.NewDecoder(r).Decode(&nationResp); err != nil {
+			return nil, err
+		}
+
+		country := getCountry(nationResp)
+		if country == nil {
+			return nil, ErrNoCountry
+		}
+
+		return &Result{
+			Value:       country.CountryID,
+			Probability: country.Probability,
+			Count:       nationResp.Count,
+			Countries:   topCountries(nationResp, topN),
+		}, nil
+	}
+}
+
+// getCountry returns the most probable country or nil if there are none.
+func getCountry(countries response.NationalitysResponse) *response.NationalityResponse {
+	var maxProbability float64
+	var idx int
+
+	c := countries.Countries
+	if len(c) == 0 {
+		return nil
+	}
+
+	for i := 0; i < len(c); i++ {
+		if c[i].Probability > maxProbability {
+			maxProbability = c[i].Probability
+			idx = i
+		}
+	}
+
+	return &countries.Countries[idx]
+}
+
+// topCountries returns n most probable countries sorted by probability.
+func topCountries(countries response.NationalitysResponse, n int) []models.Country {
+	c := make([]models.Country, 0, len(countries.Countries))
+	for _, country := range countries.Countries {
+		c = append(c, models.Country{
+			CountryID:   country.CountryID,
+			Probability: country.Probability,
+		})
+	}
+
+	return limitCountries(c, n)
+}
+
+// limitCountries sorts countries by probability and keeps n most probable of them, all if n <= 0.
+func limitCountries(c []models.Country, n int) []models.Country {
+	sort.SliceStable(c, func(i, j int) bool {
+		return c[i].Probability > c[j].Probability
+	})
+
+	if n > 0 && len(c) > n {
+		c = c[:n]
+	}
+
+	return c
+}
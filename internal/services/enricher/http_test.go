@@ -0,0 +1,56 @@
+package enricher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPProviderHidesAPIKey(t *testing.T) {
+	const key = "SECRET-KEY"
+
+	// a closed server refuses connections.
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	var logs bytes.Buffer
+	client := &http.Client{
+		Transport: newRetryTransport(http.DefaultTransport, RetryPolicy{
+			MaxAttempts: 2,
+			BaseDelay:   time.Millisecond,
+		}, slog.New(slog.NewTextHandler(&logs, nil))),
+	}
+
+	p := NewAgeProvider(srv.URL, client, WithAPIKey(key))
+
+	_, err := p.Enrich(context.Background(), Query{Name: "ivan"})
+	if err == nil {
+		t.Fatal("Enrich() error = nil, want connection error")
+	}
+
+	var ce *CallError
+	if !errors.As(err, &ce) {
+		t.Fatalf("Enrich() error = %T, want *CallError", err)
+	}
+
+	for what, s := range map[string]string{
+		"error":      err.Error(),
+		"call error": ce.Call.Error,
+		"call URL":   ce.Call.URL,
+		"retry logs": logs.String(),
+	} {
+		if strings.Contains(s, key) {
+			t.Errorf("%s contains API key: %s", what, s)
+		}
+	}
+
+	if !strings.Contains(logs.String(), "retrying") {
+		t.Errorf("request isn't retried, logs: %s", logs.String())
+	}
+}
@@ -0,0 +1,124 @@
+package enricher
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/config"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"io"
+	"os"
+	"strings"
+)
+
+// cyrillicToLatin transliteration of Russian and Ukrainian letters.
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// Normalizer brings names to the form sent to providers and used as a cache key.
+type Normalizer struct {
+	transliterate bool
+	diminutives   map[string]string
+}
+
+// NewNormalizer returns a normalizer which trims, case-folds and NFC-normalizes names,
+// optionally transliterates Cyrillic to Latin and replaces diminutives by full names.
+// Diminutives are looked up both before and after transliteration.
+func NewNormalizer(transliterate bool, diminutives map[string]string) *Normalizer {
+	return &Normalizer{
+		transliterate: transliterate,
+		diminutives:   diminutives,
+	}
+}
+
+// newNormalizer returns normalizer configured by cfg.
+func newNormalizer(cfg *config.Config) (*Normalizer, error) {
+	var diminutives map[string]string
+	if cfg.Enricher.Normalize.Diminutives != "" {
+		d, err := LoadDiminutives(cfg.Enricher.Normalize.Diminutives)
+		if err != nil {
+			return nil, err
+		}
+		diminutives = d
+	}
+
+	return NewNormalizer(cfg.Enricher.Normalize.Transliterate, diminutives), nil
+}
+
+// Normalize returns normalized name.
+func (n *Normalizer) Normalize(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	name = norm.NFC.String(cases.Fold().String(name))
+	name = n.fullName(name)
+
+	if n.transliterate {
+		name = n.fullName(transliterate(name))
+	}
+
+	return name
+}
+
+// fullName returns full name of diminutive or name itself.
+func (n *Normalizer) fullName(name string) string {
+	if full, ok := n.diminutives[name]; ok {
+		return full
+	}
+
+	return name
+}
+
+// transliterate replaces Cyrillic letters of lowercase name by Latin ones.
+func transliterate(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+
+	for _, r := range name {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// LoadDiminutives loads diminutive to full name dictionary from CSV file with header
+// diminutive,name. Both names are case-folded.
+func LoadDiminutives(path string) (map[string]string, error) {
+	const op = "services.enricher.LoadDiminutives"
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+
+	if _, err := r.Read(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	diminutives := make(map[string]string)
+	base := NewNormalizer(false, nil)
+
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return diminutives, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		diminutives[base.Normalize(row[0])] = base.Normalize(row[1])
+	}
+}
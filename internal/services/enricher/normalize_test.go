@@ -0,0 +1,76 @@
+package enricher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizer(t *testing.T) {
+	diminutives := map[string]string{"дима": "дмитрий", "sasha": "aleksandr"}
+
+	tests := []struct {
+		name          string
+		transliterate bool
+		in            string
+		want          string
+	}{
+		{name: "trim and fold", in: "  Alex ", want: "alex"},
+		{name: "collapse spaces", in: "anna \t maria", want: "anna maria"},
+		{name: "cyrillic fold", in: "НАТАЛЬЯ", want: "наталья"},
+		{name: "NFC", in: "\u0418\u0306оханн", want: "йоханн"},
+		{name: "diminutive", in: "Дима", want: "дмитрий"},
+		{name: "transliterate", transliterate: true, in: "НАТАЛЬЯ", want: "natalya"},
+		{name: "diminutive transliterated", transliterate: true, in: "Дима", want: "dmitriy"},
+		{name: "diminutive after transliteration", transliterate: true, in: "Саша", want: "aleksandr"},
+		{name: "latin untouched", transliterate: true, in: "John", want: "john"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewNormalizer(tt.transliterate, diminutives)
+
+			got := n.Normalize(tt.in)
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if again := n.Normalize(got); again != got {
+				t.Errorf("Normalize(%q) = %q, want it unchanged", got, again)
+			}
+		})
+	}
+}
+
+func TestDatasetNormalizedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.csv")
+	data := "name,attribute,value,probability,count,country_id\n" +
+		"Дмитрий,age,35,0,10,\n" +
+		"NATALYA,gender,female,0.98,20,\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	n := NewNormalizer(true, map[string]string{"dima": "dmitriy"})
+	ds, err := LoadDataset(path, n)
+	if err != nil {
+		t.Fatalf("LoadDataset() error = %v", err)
+	}
+
+	tests := []struct {
+		attr Attribute
+		name string
+		want string
+	}{
+		{attr: AttributeAge, name: "Дима", want: "35"},
+		{attr: AttributeAge, name: n.Normalize("дмитрий"), want: "35"},
+		{attr: AttributeGender, name: "Наталья", want: "female"},
+		{attr: AttributeGender, name: " natalya ", want: "female"},
+	}
+
+	for _, tt := range tests {
+		records := ds.Lookup(tt.attr, "", tt.name)
+		if len(records) == 0 || records[0].Value != tt.want {
+			t.Errorf("Lookup(%s, %q) = %v, want %s", tt.attr, tt.name, records, tt.want)
+		}
+	}
+}
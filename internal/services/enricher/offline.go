@@ -0,0 +1,318 @@
+package enricher
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/config"
+	"github.com/binaryty/enricher-service/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ProviderOfflineAge         = "offline-age"
+	ProviderOfflineGender      = "offline-gender"
+	ProviderOfflineNationality = "offline-nationality"
+)
+
+var (
+	ErrNameNotFound   = errors.New("name not found in dataset")
+	ErrInvalidDataset = errors.New("invalid dataset")
+)
+
+func init() {
+	for name, attr := range map[string]Attribute{
+		ProviderOfflineAge:         AttributeAge,
+		ProviderOfflineGender:      AttributeGender,
+		ProviderOfflineNationality: AttributeNationality,
+	} {
+		name, attr := name, attr
+
+		Register(name, func(cfg *config.Config, _ *http.Client) (Provider, error) {
+			n, err := newNormalizer(cfg)
+			if err != nil {
+				return nil, err
+			}
+
+			ds, err := loadDataset(cfg.Enricher.Offline.Dataset, n)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewOfflineProvider(name, attr, ds, cfg.Enricher.TopCountries), nil
+		})
+	}
+}
+
+// Record a single statistic of a name from dataset.
+type Record struct {
+	Value       string
+	Probability float64
+	Count       int64
+}
+
+// Dataset a local name statistics dataset.
+//
+// Every record is a row of (name, attribute, value, probability, count, country_id):
+// age and gender rows hold the most probable value of a name optionally localized
+// to country_id, nationality rows hold one country of the name distribution each.
+type Dataset struct {
+	records    map[string][]Record
+	normalizer *Normalizer
+}
+
+var (
+	datasetsMu sync.Mutex
+	datasets   = make(map[string]*Dataset)
+)
+
+// loadDataset loads dataset by path once and shares it between providers.
+func loadDataset(path string, n *Normalizer) (*Dataset, error) {
+	datasetsMu.Lock()
+	defer datasetsMu.Unlock()
+
+	if ds, ok := datasets[path]; ok {
+		return ds, nil
+	}
+
+	ds, err := LoadDataset(path, n)
+	if err != nil {
+		return nil, err
+	}
+	datasets[path] = ds
+
+	return ds, nil
+}
+
+// LoadDataset loads dataset from CSV file or from names table of SQLite database
+// depending on file extension. Names are keyed by n, the one of Enricher.
+func LoadDataset(path string, n *Normalizer) (*Dataset, error) {
+	const op = "services.enricher.LoadDataset"
+
+	ds := &Dataset{
+		records:    make(map[string][]Record),
+		normalizer: n,
+	}
+
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		err = ds.loadCSV(path)
+	case ".db", ".sqlite", ".sqlite3":
+		err = ds.loadSQLite(path)
+	default:
+		err = fmt.Errorf("%w: unsupported file %q", ErrInvalidDataset, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ds, nil
+}
+
+// loadCSV loads dataset from CSV file with header
+// name,attribute,value,probability,count,country_id.
+func (ds *Dataset) loadCSV(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 6
+
+	if _, err := r.Read(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDataset, err)
+	}
+
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidDataset, err)
+		}
+
+		probability, err := parseFloat(row[3])
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidDataset, err)
+		}
+
+		count, err := parseInt(row[4])
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidDataset, err)
+		}
+
+		if err := ds.add(row[0], row[1], row[2], probability, count, row[5]); err != nil {
+			return err
+		}
+	}
+}
+
+// loadSQLite loads dataset from names table of SQLite database with the same columns as CSV.
+func (ds *Dataset) loadSQLite(path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	rows, err := db.Query(`SELECT name, attribute, value, COALESCE(probability, 0), COALESCE(count, 0),
+		COALESCE(country_id, '') FROM names`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var (
+			name, attr, value, countryID string
+			probability                  float64
+			count                        int64
+		)
+		if err := rows.Scan(&name, &attr, &value, &probability, &count, &countryID); err != nil {
+			return err
+		}
+
+		if err := ds.add(name, attr, value, probability, count, countryID); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// add validates and adds a record to dataset.
+func (ds *Dataset) add(name, attr, value string, probability float64, count int64, countryID string) error {
+	switch Attribute(attr) {
+	case AttributeAge:
+		if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+			return fmt.Errorf("%w: age of %q: %w", ErrInvalidDataset, name, err)
+		}
+	case AttributeGender:
+	case AttributeNationality:
+		countryID = ""
+	default:
+		return fmt.Errorf("%w: %w: %s", ErrInvalidDataset, ErrUnknownAttribute, attr)
+	}
+
+	key := ds.key(Attribute(attr), countryID, name)
+	ds.records[key] = append(ds.records[key], Record{
+		Value:       value,
+		Probability: probability,
+		Count:       count,
+	})
+
+	return nil
+}
+
+// Lookup returns records of name attribute, localized ones first.
+func (ds *Dataset) Lookup(attr Attribute, countryID, name string) []Record {
+	if countryID != "" {
+		if records, ok := ds.records[ds.key(attr, countryID, name)]; ok {
+			return records
+		}
+	}
+
+	return ds.records[ds.key(attr, "", name)]
+}
+
+// key returns key of records in dataset.
+func (ds *Dataset) key(attr Attribute, countryID, name string) string {
+	return string(attr) + ":" + strings.ToUpper(countryID) + ":" + ds.normalizer.Normalize(name)
+}
+
+// OfflineProvider a provider backed by a local dataset.
+type OfflineProvider struct {
+	name      string
+	attribute Attribute
+	dataset   *Dataset
+	topN      int
+}
+
+// NewOfflineProvider returns a provider of attribute backed by dataset.
+// Nationality results keep at most topN most probable countries, all of them if topN <= 0.
+func NewOfflineProvider(name string, attribute Attribute, dataset *Dataset, topN int) *OfflineProvider {
+	return &OfflineProvider{
+		name:      name,
+		attribute: attribute,
+		dataset:   dataset,
+		topN:      topN,
+	}
+}
+
+// Name returns provider name.
+func (p *OfflineProvider) Name() string {
+	return p.name
+}
+
+// Attribute returns attribute produced by provider.
+func (p *OfflineProvider) Attribute() Attribute {
+	return p.attribute
+}
+
+// Enrich get attribute value from dataset.
+func (p *OfflineProvider) Enrich(_ context.Context, q Query) (*Result, error) {
+	records := p.dataset.Lookup(p.attribute, q.CountryID, q.Name)
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: %w: %s", p.name, ErrNameNotFound, q.Name)
+	}
+
+	if p.attribute != AttributeNationality {
+		return &Result{
+			Provider:    p.name,
+			Attribute:   p.attribute,
+			Value:       records[0].Value,
+			Probability: records[0].Probability,
+			Count:       records[0].Count,
+		}, nil
+	}
+
+	countries := make([]models.Country, 0, len(records))
+	for _, r := range records {
+		countries = append(countries, models.Country{
+			CountryID:   strings.ToUpper(r.Value),
+			Probability: r.Probability,
+		})
+	}
+
+	countries = limitCountries(countries, p.topN)
+
+	return &Result{
+		Provider:    p.name,
+		Attribute:   p.attribute,
+		Value:       countries[0].CountryID,
+		Probability: countries[0].Probability,
+		Count:       records[0].Count,
+		Countries:   countries,
+	}, nil
+}
+
+// parseFloat parses float, empty string is zero.
+func parseFloat(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseInt parses integer, empty string is zero.
+func parseInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(s, 10, 64)
+}
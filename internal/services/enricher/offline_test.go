@@ -0,0 +1,163 @@
+package enricher
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/binaryty/enricher-service/internal/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const datasetHeader = "name,attribute,value,probability,count,country_id\n"
+
+// writeDataset writes CSV dataset of rows to a temp file and returns its path.
+func writeDataset(t *testing.T, rows string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "names.csv")
+	if err := os.WriteFile(path, []byte(datasetHeader+rows), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadDatasetErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rows string
+	}{
+		{name: "missing field", rows: "anna,age,30,0,10\n"},
+		{name: "extra field", rows: "anna,age,30,0,10,,x\n"},
+		{name: "bad probability", rows: "anna,gender,female,high,10,\n"},
+		{name: "bad count", rows: "anna,gender,female,0.9,many,\n"},
+		{name: "bad age", rows: "anna,age,thirty,0,10,\n"},
+		{name: "negative age", rows: "anna,age,-1,0,10,\n"},
+		{name: "unknown attribute", rows: "anna,height,170,0,10,\n"},
+		{name: "bad row after good ones", rows: "anna,age,30,0,10,\nanna,gender,female,0.9,x,\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadDataset(writeDataset(t, tt.rows), NewNormalizer(false, nil))
+			if !errors.Is(err, ErrInvalidDataset) {
+				t.Errorf("LoadDataset() error = %v, want ErrInvalidDataset", err)
+			}
+		})
+	}
+
+	t.Run("empty file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "names.csv")
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadDataset(path, NewNormalizer(false, nil)); !errors.Is(err, ErrInvalidDataset) {
+			t.Errorf("LoadDataset() error = %v, want ErrInvalidDataset", err)
+		}
+	})
+
+	t.Run("unsupported file", func(t *testing.T) {
+		if _, err := LoadDataset("names.json", NewNormalizer(false, nil)); !errors.Is(err, ErrInvalidDataset) {
+			t.Errorf("LoadDataset() error = %v, want ErrInvalidDataset", err)
+		}
+	})
+}
+
+func TestDatasetLookup(t *testing.T) {
+	rows := "anna,age,30,0,100,\n" +
+		"anna,age,27,0,40,ua\n" +
+		"anna,gender,female,0.98,100,\n" +
+		"anna,nationality,ua,0.4,100,\n" +
+		"anna,nationality,ru,0.3,100,pl\n" +
+		"ivan,gender,male,,,RU\n"
+
+	ds, err := LoadDataset(writeDataset(t, rows), NewNormalizer(false, nil))
+	if err != nil {
+		t.Fatalf("LoadDataset() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		attr      Attribute
+		countryID string
+		query     string
+		want      []Record
+	}{
+		{name: "global", attr: AttributeAge, query: "anna", want: []Record{{Value: "30", Count: 100}}},
+		{name: "localized first", attr: AttributeAge, countryID: "UA", query: "anna", want: []Record{{Value: "27", Count: 40}}},
+		{name: "lowercase country", attr: AttributeAge, countryID: "ua", query: "Anna", want: []Record{{Value: "27", Count: 40}}},
+		{name: "global without localized", attr: AttributeGender, countryID: "UA", query: "anna", want: []Record{{Value: "female", Probability: 0.98, Count: 100}}},
+		{
+			name: "nationality isn't localized", attr: AttributeNationality, countryID: "PL", query: "anna",
+			want: []Record{{Value: "ua", Probability: 0.4, Count: 100}, {Value: "ru", Probability: 0.3, Count: 100}},
+		},
+		{name: "empty numbers", attr: AttributeGender, countryID: "RU", query: "ivan", want: []Record{{Value: "male"}}},
+		{name: "only localized", attr: AttributeGender, query: "ivan"},
+		{name: "unknown name", attr: AttributeAge, query: "zzz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ds.Lookup(tt.attr, tt.countryID, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lookup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDatasetSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.db")
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE names (name TEXT, attribute TEXT, value TEXT, probability REAL, count INTEGER, country_id TEXT);
+		INSERT INTO names VALUES ('anna', 'age', '30', NULL, 100, NULL), ('anna', 'age', '27', NULL, 40, 'UA');`); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	ds, err := LoadDataset(path, NewNormalizer(false, nil))
+	if err != nil {
+		t.Fatalf("LoadDataset() error = %v", err)
+	}
+
+	if got := ds.Lookup(AttributeAge, "UA", "anna"); len(got) != 1 || got[0].Value != "27" {
+		t.Errorf("Lookup(UA) = %v, want 27", got)
+	}
+	if got := ds.Lookup(AttributeAge, "", "anna"); len(got) != 1 || got[0].Value != "30" {
+		t.Errorf("Lookup() = %v, want 30", got)
+	}
+}
+
+func TestOfflineProvider(t *testing.T) {
+	rows := "anna,nationality,ru,0.3,100,\n" +
+		"anna,nationality,ua,0.4,100,\n" +
+		"anna,nationality,pl,0.1,100,\n"
+
+	ds, err := LoadDataset(writeDataset(t, rows), NewNormalizer(false, nil))
+	if err != nil {
+		t.Fatalf("LoadDataset() error = %v", err)
+	}
+
+	p := NewOfflineProvider(ProviderOfflineNationality, AttributeNationality, ds, 2)
+
+	res, err := p.Enrich(context.Background(), Query{Name: "anna"})
+	if err != nil {
+		t.Fatalf("Enrich() error = %v", err)
+	}
+
+	want := []models.Country{{CountryID: "UA", Probability: 0.4}, {CountryID: "RU", Probability: 0.3}}
+	if res.Value != "UA" || !reflect.DeepEqual(res.Countries, want) {
+		t.Errorf("Enrich() = %s %v, want UA %v", res.Value, res.Countries, want)
+	}
+
+	if _, err := p.Enrich(context.Background(), Query{Name: "zzz"}); !errors.Is(err, ErrNameNotFound) {
+		t.Errorf("Enrich(zzz) error = %v, want ErrNameNotFound", err)
+	}
+}
@@ -0,0 +1,101 @@
+package enricher
+
+import (
+	"context"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/config"
+	"github.com/binaryty/enricher-service/internal/models"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Attribute a person attribute produced by a provider.
+type Attribute string
+
+const (
+	AttributeAge         Attribute = models.AttributeAge
+	AttributeGender      Attribute = models.AttributeGender
+	AttributeNationality Attribute = models.AttributeNationality
+)
+
+// Result an enrichment result of a single provider.
+type Result struct {
+	Provider    string
+	Attribute   Attribute
+	Value       string
+	Probability float64
+	Count       int64
+	Countries   []models.Country
+	Sources     []string
+	Call        *models.EnrichmentCall
+}
+
+// Query a name to enrich, optionally localized to a country.
+type Query struct {
+	Name      string
+	CountryID string
+}
+
+// key returns form of query used to identify it, names are normalized by Enricher.
+func (q Query) key() string {
+	return q.CountryID + ":" + q.Name
+}
+
+// Provider a source of a single person attribute.
+type Provider interface {
+	Name() string
+	Attribute() Attribute
+	Enrich(ctx context.Context, q Query) (*Result, error)
+}
+
+// Factory creates a provider from config.
+type Factory func(cfg *config.Config, client *http.Client) (Provider, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register makes a provider factory available by name.
+// It panics if Register is called twice with the same name or factory is nil.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("enricher: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("enricher: Register called twice for provider " + name)
+	}
+
+	factories[name] = factory
+}
+
+// Providers returns a sorted list of the names of the registered providers.
+func Providers() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// newProvider creates a registered provider by name.
+func newProvider(name string, cfg *config.Config, client *http.Client) (Provider, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+
+	return factory(cfg, client)
+}
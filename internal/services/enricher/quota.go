@@ -0,0 +1,124 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/models"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	headerRateLimit     = "X-Rate-Limit-Limit"
+	headerRateRemaining = "X-Rate-Limit-Remaining"
+	headerRateReset     = "X-Rate-Limit-Reset"
+)
+
+var ErrQuotaExhausted = errors.New("quota exhausted")
+
+// Quota tracks the remaining request quota of a provider reported by the API.
+type Quota struct {
+	mu        sync.Mutex
+	provider  string
+	floor     int
+	queue     bool
+	known     bool
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// NewQuota returns a quota tracker which refuses requests once remaining quota
+// reaches floor, or makes them wait for the quota reset if queue is set.
+func NewQuota(provider string, floor int, queue bool) *Quota {
+	return &Quota{
+		provider: provider,
+		floor:    floor,
+		queue:    queue,
+	}
+}
+
+// Acquire reserves n requests of the quota.
+func (q *Quota) Acquire(ctx context.Context, n int) error {
+	for {
+		q.mu.Lock()
+
+		if q.known && !q.reset.IsZero() && !time.Now().Before(q.reset) {
+			// the quota has been reset, wait for the API to report a new one.
+			q.known = false
+		}
+
+		if !q.known || q.remaining-n >= q.floor {
+			if q.known {
+				q.remaining -= n
+			}
+			q.mu.Unlock()
+
+			return nil
+		}
+
+		reset := q.reset
+		q.mu.Unlock()
+
+		if !q.queue || reset.IsZero() {
+			return fmt.Errorf("%s: %w", q.provider, ErrQuotaExhausted)
+		}
+
+		timer := time.NewTimer(time.Until(reset))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Update updates quota from rate limit response headers.
+func (q *Quota) Update(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get(headerRateRemaining))
+	if err != nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.known = true
+	q.remaining = remaining
+
+	if limit, err := strconv.Atoi(h.Get(headerRateLimit)); err == nil {
+		q.limit = limit
+	}
+
+	if reset, err := strconv.Atoi(h.Get(headerRateReset)); err == nil {
+		q.reset = time.Now().Add(time.Duration(reset) * time.Second)
+	}
+}
+
+// Status returns current quota state.
+func (q *Quota) Status() models.QuotaStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := models.QuotaStatus{
+		Provider: q.provider,
+		Floor:    q.floor,
+		Known:    q.known,
+	}
+
+	if q.known {
+		s.Limit = q.limit
+		s.Remaining = q.remaining
+		s.Exhausted = q.remaining <= q.floor
+		if !q.reset.IsZero() {
+			reset := q.reset
+			s.Reset = &reset
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,48 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestQuotaFloor(t *testing.T) {
+	tests := []struct {
+		name      string
+		floor     int
+		remaining int // -1 if the API hasn't reported quota
+		reset     int
+		acquire   []int
+		wantErr   []bool
+	}{
+		{name: "unknown quota", floor: 10, remaining: -1, acquire: []int{100}, wantErr: []bool{false}},
+		{name: "above floor", floor: 10, remaining: 15, reset: 60, acquire: []int{5}, wantErr: []bool{false}},
+		{name: "reaches floor", floor: 10, remaining: 15, reset: 60, acquire: []int{5, 1}, wantErr: []bool{false, true}},
+		{name: "batch crosses floor", floor: 10, remaining: 15, reset: 60, acquire: []int{6}, wantErr: []bool{true}},
+		{name: "no floor", floor: 0, remaining: 2, reset: 60, acquire: []int{1, 1, 1}, wantErr: []bool{false, false, true}},
+		{name: "reset passed", floor: 10, remaining: 0, reset: 0, acquire: []int{5}, wantErr: []bool{false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQuota("test", tt.floor, false)
+
+			if tt.remaining >= 0 {
+				h := make(http.Header)
+				h.Set(headerRateLimit, "1000")
+				h.Set(headerRateRemaining, strconv.Itoa(tt.remaining))
+				h.Set(headerRateReset, strconv.Itoa(tt.reset))
+				q.Update(h)
+			}
+
+			for i, n := range tt.acquire {
+				err := q.Acquire(context.Background(), n)
+				if tt.wantErr[i] != errors.Is(err, ErrQuotaExhausted) {
+					t.Fatalf("Acquire(%d) #%d = %v, want exhausted %v", n, i, err, tt.wantErr[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package enricher
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes how outbound enrichment calls are retried.
+type RetryPolicy struct {
+	MaxAttempts       int
+	BaseDelay         time.Duration
+	MaxDelay          time.Duration
+	Jitter            float64
+	RetryableStatuses []int
+}
+
+// retryTransport retries failed requests according to the policy.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+	log    *slog.Logger
+}
+
+// newRetryTransport wraps next with retries.
+func newRetryTransport(next http.RoundTripper, policy RetryPolicy, log *slog.Logger) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &retryTransport{
+		next:   next,
+		policy: policy,
+		log:    log,
+	}
+}
+
+// RoundTrip executes request and retries it on transient failures.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	const op = "services.enricher.retryTransport.RoundTrip"
+	logger := t.log.With("operation", op, slog.String("url", redactURL(req.URL)))
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.Body != nil {
+			if req.GetBody == nil {
+				return t.next.RoundTrip(req)
+			}
+
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := t.next.RoundTrip(req)
+
+		if attempt >= t.policy.MaxAttempts || !t.retryable(req.Context(), resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				// waiting longer than MaxDelay would hold the caller, the response is returned as is.
+				if t.policy.MaxDelay > 0 && retryAfter > t.policy.MaxDelay {
+					return resp, err
+				}
+				if retryAfter > delay {
+					delay = retryAfter
+				}
+			}
+
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		attrs := []any{slog.Int("attempt", attempt), slog.Duration("delay", delay)}
+		if err != nil {
+			attrs = append(attrs, slog.String("[ERROR]", err.Error()))
+		} else {
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		}
+		logger.Warn("retrying enrichment request", attrs...)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether the request should be retried.
+func (t *retryTransport) retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	for _, code := range t.policy.RetryableStatuses {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns exponential delay with jitter before the next attempt.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	delay := float64(t.policy.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if t.policy.MaxDelay > 0 && delay > float64(t.policy.MaxDelay) {
+		delay = float64(t.policy.MaxDelay)
+	}
+
+	if t.policy.Jitter > 0 {
+		delay *= 1 + t.policy.Jitter*(2*rand.Float64()-1)
+	}
+
+	return time.Duration(delay)
+}
+
+// parseRetryAfter parses Retry-After header in seconds or HTTP-date form.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date), true
+	}
+
+	return 0, false
+}
+
+// redactURL returns URL without user password and API key for logging.
+func redactURL(u *url.URL) string {
+	redacted := *u
+
+	query := redacted.Query()
+	if query.Has(paramAPIKey) {
+		query.Set(paramAPIKey, "xxxxx")
+		redacted.RawQuery = query.Encode()
+	}
+
+	return redacted.Redacted()
+}
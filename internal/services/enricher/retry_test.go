@@ -0,0 +1,137 @@
+package enricher
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripFunc an http.RoundTripper of function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRetryTransport(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:       3,
+		BaseDelay:         time.Millisecond,
+		MaxDelay:          time.Second,
+		RetryableStatuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	}
+
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		err        error
+		wantCalls  int
+		wantStatus int
+		wantErr    bool
+	}{
+		{name: "success", statuses: []int{200}, wantCalls: 1, wantStatus: 200},
+		{name: "retried until success", statuses: []int{503, 503, 200}, wantCalls: 3, wantStatus: 200},
+		{name: "attempts exhausted", statuses: []int{503, 503, 503, 200}, wantCalls: 3, wantStatus: 503},
+		{name: "not retryable status", statuses: []int{404, 200}, wantCalls: 1, wantStatus: 404},
+		{name: "transport error", err: errors.New("refused"), wantCalls: 3, wantErr: true},
+		{name: "retry after within max delay", statuses: []int{429, 200}, retryAfter: "0", wantCalls: 2, wantStatus: 200},
+		{name: "retry after beyond max delay", statuses: []int{429, 200}, retryAfter: "3600", wantCalls: 1, wantStatus: 429},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			next := roundTripFunc(func(*http.Request) (*http.Response, error) {
+				calls++
+				if tt.err != nil {
+					return nil, tt.err
+				}
+
+				resp := &http.Response{
+					StatusCode: tt.statuses[calls-1],
+					Header:     make(http.Header),
+					Body:       io.NopCloser(strings.NewReader("")),
+				}
+				if tt.retryAfter != "" {
+					resp.Header.Set("Retry-After", tt.retryAfter)
+				}
+
+				return resp, nil
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "http://example.com/?name=a", nil)
+
+			start := time.Now()
+			resp, err := newRetryTransport(next, policy, discardLogger()).RoundTrip(req)
+
+			if time.Since(start) > policy.MaxDelay {
+				t.Errorf("request took %s, longer than max delay", time.Since(start))
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tr := &retryTransport{policy: RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: 0.2}}
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 1, min: 80 * time.Millisecond, max: 120 * time.Millisecond},
+		{attempt: 2, min: 160 * time.Millisecond, max: 240 * time.Millisecond},
+		{attempt: 3, min: 320 * time.Millisecond, max: 480 * time.Millisecond},
+		{attempt: 10, min: 800 * time.Millisecond, max: 1200 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			if d := tr.backoff(tt.attempt); d < tt.min || d > tt.max {
+				t.Fatalf("backoff(%d) = %s, want within [%s, %s]", tt.attempt, d, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", wantOK: false},
+		{value: "5", want: 5 * time.Second, wantOK: true},
+		{value: "-1", wantOK: false},
+		{value: "soon", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	got, ok := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if !ok || got < 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(date) = %s, %v, want about a minute", got, ok)
+	}
+}
@@ -0,0 +1,50 @@
+package enricher
+
+import (
+	"context"
+	"golang.org/x/sync/singleflight"
+	"time"
+)
+
+// sharedProvider coalesces concurrent calls of provider for the same query.
+type sharedProvider struct {
+	Provider
+	group   singleflight.Group
+	timeout time.Duration
+}
+
+// withSharedCalls wraps provider with in-flight calls coalescing, a shared call is
+// canceled after timeout. There is no timeout if timeout <= 0.
+func withSharedCalls(p Provider, timeout time.Duration) Provider {
+	return &sharedProvider{
+		Provider: p,
+		timeout:  timeout,
+	}
+}
+
+// Enrich joins an in-flight call for the same query or starts a new one.
+func (p *sharedProvider) Enrich(ctx context.Context, q Query) (*Result, error) {
+	// the shared call must outlive any single caller, each caller still honours its own ctx.
+	// It has its own timeout so that a hung upstream doesn't hold the callers joined to it.
+	ch := p.group.DoChan(q.key(), func() (interface{}, error) {
+		callCtx := context.WithoutCancel(ctx)
+		if p.timeout > 0 {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithTimeout(callCtx, p.timeout)
+			defer cancel()
+		}
+
+		return p.Provider.Enrich(callCtx, q)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+
+		return res.Val.(*Result), nil
+	}
+}
@@ -0,0 +1,40 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSharedProviderTimeout(t *testing.T) {
+	hung := &fakeProvider{
+		name: "hung",
+		attr: AttributeAge,
+		enrich: func(ctx context.Context, _ Query) (*Result, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	p := withSharedCalls(hung, 20*time.Millisecond)
+
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := p.Enrich(context.Background(), Query{Name: "ivan"})
+			done <- err
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Enrich() = %v, want context.DeadlineExceeded", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("shared call isn't canceled by timeout")
+		}
+	}
+}
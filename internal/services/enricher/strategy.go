@@ -0,0 +1,272 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"github.com/binaryty/enricher-service/internal/models"
+	"math"
+	"strconv"
+)
+
+const (
+	StrategyFallback  = "fallback"
+	StrategyConsensus = "consensus"
+)
+
+var ErrUnknownStrategy = errors.New("unknown strategy")
+
+// resolve gets attribute value from providers of the same attribute according to its strategy.
+// It returns the provider calls made to get it as well.
+func (e *Enricher) resolve(ctx context.Context, q Query, attr Attribute, providers []Provider) (*Result, []models.EnrichmentCall, error) {
+	if len(providers) == 1 {
+		res, err := providers[0].Enrich(ctx, q)
+		calls := appendCall(nil, res, err)
+		if err != nil {
+			return nil, calls, err
+		}
+		if res.Value == "" {
+			return nil, calls, ErrNoResult
+		}
+
+		return withSources(res, res.Provider), calls, nil
+	}
+
+	if e.strategies[attr] == StrategyConsensus {
+		return e.consensus(ctx, q, attr, providers)
+	}
+
+	return e.fallback(ctx, q, providers)
+}
+
+// fallback asks providers one by one until one of them returns a confident result.
+// The most probable of low-probability results is used if none is confident.
+func (e *Enricher) fallback(ctx context.Context, q Query, providers []Provider) (*Result, []models.EnrichmentCall, error) {
+	var (
+		best  *Result
+		errs  []error
+		calls []models.EnrichmentCall
+	)
+
+	for _, p := range providers {
+		res, err := p.Enrich(ctx, q)
+		calls = appendCall(calls, res, err)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if res.Value == "" {
+			continue
+		}
+
+		if e.confident(res) {
+			return withSources(res, res.Provider), calls, nil
+		}
+
+		if best == nil || res.Probability > best.Probability {
+			best = res
+		}
+	}
+
+	if best != nil {
+		return withSources(best, best.Provider), calls, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, calls, ErrNoResult
+	}
+
+	return nil, calls, errors.Join(errs...)
+}
+
+// confident reports whether result probability isn't below the threshold,
+// results without probability are always confident.
+func (e *Enricher) confident(res *Result) bool {
+	return res.Probability == 0 || res.Probability >= e.minProbability
+}
+
+// consensus asks all providers at once and combines their results weighted by provider weights.
+func (e *Enricher) consensus(ctx context.Context, q Query, attr Attribute, providers []Provider) (*Result, []models.EnrichmentCall, error) {
+	type answer struct {
+		res *Result
+		err error
+	}
+
+	answers := make([]answer, len(providers))
+	done := make(chan struct{}, len(providers))
+
+	for i, p := range providers {
+		go func(i int, p Provider) {
+			res, err := p.Enrich(ctx, q)
+			answers[i] = answer{res: res, err: err}
+			done <- struct{}{}
+		}(i, p)
+	}
+
+	for range providers {
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-done:
+		}
+	}
+
+	var (
+		results []*Result
+		errs    []error
+		calls   []models.EnrichmentCall
+	)
+	for _, a := range answers {
+		calls = appendCall(calls, a.res, a.err)
+
+		switch {
+		case a.err != nil:
+			errs = append(errs, a.err)
+		case a.res.Value != "":
+			results = append(results, a.res)
+		}
+	}
+
+	if len(results) == 0 {
+		if len(errs) == 0 {
+			return nil, calls, ErrNoResult
+		}
+
+		return nil, calls, errors.Join(errs...)
+	}
+
+	if attr == AttributeAge {
+		return e.averageAge(results), calls, nil
+	}
+
+	return e.vote(results), calls, nil
+}
+
+// weight returns weight of provider, 1 if not configured.
+func (e *Enricher) weight(provider string) float64 {
+	if w, ok := e.weights[provider]; ok {
+		return w
+	}
+
+	return 1
+}
+
+// averageAge combines age results into their weighted mean.
+func (e *Enricher) averageAge(results []*Result) *Result {
+	var (
+		sum, total float64
+		count      int64
+		sources    []string
+	)
+
+	for _, res := range results {
+		age, err := strconv.ParseFloat(res.Value, 64)
+		if err != nil {
+			continue
+		}
+
+		w := e.weight(res.Provider)
+		sum += w * age
+		total += w
+		count += res.Count
+		sources = append(sources, res.Provider)
+	}
+
+	if total == 0 {
+		return withSources(results[0], results[0].Provider)
+	}
+
+	return &Result{
+		Provider:  results[0].Provider,
+		Attribute: AttributeAge,
+		Value:     strconv.Itoa(int(math.Round(sum / total))),
+		Count:     count,
+		Sources:   sources,
+	}
+}
+
+// vote combines categorical results by weighted probabilities of their values,
+// results without probability count as certain. Nationality votes are cast by
+// the whole country distribution of every provider.
+func (e *Enricher) vote(results []*Result) *Result {
+	var (
+		total   float64
+		count   int64
+		maxLen  int
+		scores  = make(map[string]float64)
+		ordered []string
+	)
+
+	cast := func(value string, score float64) {
+		if _, ok := scores[value]; !ok {
+			ordered = append(ordered, value)
+		}
+		scores[value] += score
+	}
+
+	for _, res := range results {
+		w := e.weight(res.Provider)
+		total += w
+		count += res.Count
+
+		if len(res.Countries) > 0 {
+			for _, c := range res.Countries {
+				cast(c.CountryID, w*c.Probability)
+			}
+			maxLen = max(maxLen, len(res.Countries))
+
+			continue
+		}
+
+		p := res.Probability
+		if p == 0 {
+			p = 1
+		}
+		cast(res.Value, w*p)
+	}
+
+	winner := ordered[0]
+	for _, value := range ordered[1:] {
+		if scores[value] > scores[winner] {
+			winner = value
+		}
+	}
+
+	combined := &Result{
+		Provider:  results[0].Provider,
+		Attribute: results[0].Attribute,
+		Value:     winner,
+		Count:     count,
+	}
+	if total > 0 {
+		combined.Probability = scores[winner] / total
+	}
+
+	for _, res := range results {
+		if res.Value == winner {
+			combined.Sources = append(combined.Sources, res.Provider)
+		}
+	}
+
+	if maxLen > 0 {
+		countries := make([]models.Country, 0, len(ordered))
+		for _, value := range ordered {
+			countries = append(countries, models.Country{
+				CountryID:   value,
+				Probability: scores[value] / total,
+			})
+		}
+		combined.Countries = limitCountries(countries, maxLen)
+	}
+
+	return combined
+}
+
+// withSources returns a copy of result determined by sources,
+// results may be shared between callers and must not be modified.
+func withSources(res *Result, sources ...string) *Result {
+	r := *res
+	r.Sources = sources
+
+	return &r
+}
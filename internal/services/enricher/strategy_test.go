@@ -0,0 +1,270 @@
+package enricher
+
+import (
+	"context"
+	"errors"
+	"github.com/binaryty/enricher-service/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// answer a canned provider answer of strategy tests.
+type answer struct {
+	value       string
+	probability float64
+	countries   []models.Country
+	err         error
+}
+
+// answering returns providers of attr answering in order of answers, provider i is named pi.
+func answering(attr Attribute, answers []answer) []Provider {
+	providers := make([]Provider, 0, len(answers))
+	for i, a := range answers {
+		name := "p" + string(rune('0'+i))
+		a := a
+		providers = append(providers, &fakeProvider{
+			name: name,
+			attr: attr,
+			enrich: func(context.Context, Query) (*Result, error) {
+				if a.err != nil {
+					return nil, a.err
+				}
+
+				return &Result{
+					Provider:    name,
+					Attribute:   attr,
+					Value:       a.value,
+					Probability: a.probability,
+					Countries:   a.countries,
+					Count:       1,
+				}, nil
+			},
+		})
+	}
+
+	return providers
+}
+
+func TestFallback(t *testing.T) {
+	tests := []struct {
+		name        string
+		answers     []answer
+		wantValue   string
+		wantSources []string
+		wantErr     error
+	}{
+		{
+			name:        "first confident",
+			answers:     []answer{{value: "male", probability: 0.9}, {value: "female", probability: 0.99}},
+			wantValue:   "male",
+			wantSources: []string{"p0"},
+		},
+		{
+			name:        "skips not confident",
+			answers:     []answer{{value: "male", probability: 0.3}, {value: "female", probability: 0.8}},
+			wantValue:   "female",
+			wantSources: []string{"p1"},
+		},
+		{
+			name:        "most probable of not confident",
+			answers:     []answer{{value: "male", probability: 0.3}, {value: "female", probability: 0.5}, {value: "male", probability: 0.4}},
+			wantValue:   "female",
+			wantSources: []string{"p1"},
+		},
+		{
+			name:        "no probability is confident",
+			answers:     []answer{{value: "42"}, {value: "30", probability: 0.9}},
+			wantValue:   "42",
+			wantSources: []string{"p0"},
+		},
+		{
+			name:        "skips errors",
+			answers:     []answer{{err: errors.New("down")}, {value: "female", probability: 0.9}},
+			wantValue:   "female",
+			wantSources: []string{"p1"},
+		},
+		{
+			name:        "skips unknown names",
+			answers:     []answer{{err: ErrNameNotFound}, {value: "female", probability: 0.9}},
+			wantValue:   "female",
+			wantSources: []string{"p1"},
+		},
+		{
+			name:        "skips empty values",
+			answers:     []answer{{value: ""}, {value: "male", probability: 0.9}},
+			wantValue:   "male",
+			wantSources: []string{"p1"},
+		},
+		{
+			name:    "only empty values",
+			answers: []answer{{value: ""}, {value: ""}},
+			wantErr: ErrNoResult,
+		},
+		{
+			name:    "only errors",
+			answers: []answer{{err: ErrNameNotFound}, {err: ErrNameNotFound}},
+			wantErr: ErrNameNotFound,
+		},
+		{
+			name:        "single provider",
+			answers:     []answer{{value: "male", probability: 0.1}},
+			wantValue:   "male",
+			wantSources: []string{"p0"},
+		},
+		{
+			name:    "single provider empty value",
+			answers: []answer{{value: ""}},
+			wantErr: ErrNoResult,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewWithProviders(discardLogger(), nil, WithMinProbability(0.6))
+
+			res, _, err := e.resolve(context.Background(), Query{Name: "alex"}, AttributeGender, answering(AttributeGender, tt.answers))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("resolve() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+
+			if res.Value != tt.wantValue || !reflect.DeepEqual(res.Sources, tt.wantSources) {
+				t.Errorf("resolve() = %s from %v, want %s from %v", res.Value, res.Sources, tt.wantValue, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestConsensus(t *testing.T) {
+	tests := []struct {
+		name        string
+		attr        Attribute
+		weights     map[string]float64
+		answers     []answer
+		wantValue   string
+		wantSources []string
+		wantErr     error
+	}{
+		{
+			name:        "mean age",
+			attr:        AttributeAge,
+			answers:     []answer{{value: "30"}, {value: "40"}},
+			wantValue:   "35",
+			wantSources: []string{"p0", "p1"},
+		},
+		{
+			name:        "weighted mean age",
+			attr:        AttributeAge,
+			weights:     map[string]float64{"p0": 3},
+			answers:     []answer{{value: "30"}, {value: "40"}},
+			wantValue:   "33",
+			wantSources: []string{"p0", "p1"},
+		},
+		{
+			name:        "age without failed provider",
+			attr:        AttributeAge,
+			answers:     []answer{{err: ErrNameNotFound}, {value: "40"}, {value: ""}},
+			wantValue:   "40",
+			wantSources: []string{"p1"},
+		},
+		{
+			name:        "majority vote",
+			attr:        AttributeGender,
+			answers:     []answer{{value: "male", probability: 0.9}, {value: "female", probability: 0.6}, {value: "female", probability: 0.6}},
+			wantValue:   "female",
+			wantSources: []string{"p1", "p2"},
+		},
+		{
+			name:        "weighted vote",
+			attr:        AttributeGender,
+			weights:     map[string]float64{"p0": 3},
+			answers:     []answer{{value: "male", probability: 0.9}, {value: "female", probability: 0.6}, {value: "female", probability: 0.6}},
+			wantValue:   "male",
+			wantSources: []string{"p0"},
+		},
+		{
+			name: "nationality by distributions",
+			attr: AttributeNationality,
+			answers: []answer{
+				{value: "UA", probability: 0.5, countries: []models.Country{{CountryID: "UA", Probability: 0.5}, {CountryID: "RU", Probability: 0.4}}},
+				{value: "RU", probability: 0.7, countries: []models.Country{{CountryID: "RU", Probability: 0.7}}},
+			},
+			wantValue:   "RU",
+			wantSources: []string{"p1"},
+		},
+		{
+			name:    "nothing resolved",
+			attr:    AttributeAge,
+			answers: []answer{{err: ErrNameNotFound}, {value: ""}},
+			wantErr: ErrNameNotFound,
+		},
+		{
+			name:    "only empty values",
+			attr:    AttributeGender,
+			answers: []answer{{value: ""}, {value: ""}},
+			wantErr: ErrNoResult,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewWithProviders(discardLogger(), nil,
+				WithStrategies(map[Attribute]string{tt.attr: StrategyConsensus}),
+				WithWeights(tt.weights),
+			)
+
+			res, _, err := e.resolve(context.Background(), Query{Name: "alex"}, tt.attr, answering(tt.attr, tt.answers))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("resolve() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+
+			if res.Value != tt.wantValue || !reflect.DeepEqual(res.Sources, tt.wantSources) {
+				t.Errorf("resolve() = %s from %v, want %s from %v", res.Value, res.Sources, tt.wantValue, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestDecodeAge(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantValue string
+		wantErr   error
+	}{
+		{name: "known name", body: `{"count":120,"name":"alex","age":42}`, wantValue: "42"},
+		{name: "null age", body: `{"count":0,"name":"zzyzx","age":null}`, wantErr: ErrNameNotFound},
+		{name: "zero count", body: `{"count":0,"name":"zzyzx","age":0}`, wantErr: ErrNameNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := decodeAge(strings.NewReader(tt.body))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("decodeAge() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeAge() error = %v", err)
+			}
+
+			if res.Value != tt.wantValue {
+				t.Errorf("decodeAge() = %s, want %s", res.Value, tt.wantValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/storage"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAsyncDisabled = errors.New("async enrichment is disabled")
+)
+
+// StartJobWorkers starts workers enriching queued persons until ctx is done,
+// requeues jobs left running by the previous run and every rescan interval
+// picks up queued jobs which didn't fit the queue.
+func (s *Service) StartJobWorkers(ctx context.Context, workers, queueSize int, rescan time.Duration) {
+	const op = "services.people.StartJobWorkers"
+	logger := s.log.With("operation", op)
+
+	s.jobs = make(chan models.Job, queueSize)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-s.jobs:
+					s.processJob(ctx, job)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		logger.Info("enrichment job workers stopped")
+	}()
+
+	logger.Info("enrichment job workers started", slog.Int("workers", workers))
+
+	go func() {
+		n, err := s.personProvider.RequeueRunningJobs(ctx)
+		if err != nil {
+			logger.Warn("can't requeue running jobs", slog.String("[ERROR]", err.Error()))
+		} else if n > 0 {
+			logger.Info("running jobs requeued", slog.Int64("count", n))
+		}
+
+		ticker := time.NewTicker(rescan)
+		defer ticker.Stop()
+
+		for {
+			s.rescanJobs(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// rescanJobs queues stored queued jobs while the queue has room. Jobs queued twice
+// are processed once, the second copy fails to claim the job.
+func (s *Service) rescanJobs(ctx context.Context) {
+	const op = "services.people.rescanJobs"
+	logger := s.log.With("operation", op)
+
+	room := cap(s.jobs) - len(s.jobs)
+	if room <= 0 {
+		return
+	}
+
+	jobs, err := s.personProvider.SelectQueuedJobs(ctx, room)
+	if err != nil {
+		logger.Warn("can't select queued jobs", slog.String("[ERROR]", err.Error()))
+		return
+	}
+
+	queued := 0
+	for _, job := range jobs {
+		if !s.queueJob(job) {
+			break
+		}
+		queued++
+	}
+
+	if queued > 0 {
+		logger.Debug("queued jobs picked up", slog.Int("count", queued))
+	}
+}
+
+// queueJob queues job without waiting and reports whether the queue had room.
+func (s *Service) queueJob(job models.Job) bool {
+	select {
+	case s.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// Async reports whether job workers are running.
+func (s *Service) Async() bool {
+	return s.jobs != nil
+}
+
+// EnqueuePerson saves raw person data and queues a job to enrich it.
+func (s *Service) EnqueuePerson(ctx context.Context, rawData models.RawPerson) (*models.Job, error) {
+	const op = "services.people.EnqueuePerson"
+	logger := s.log.With("operation", op)
+
+	if s.jobs == nil {
+		return nil, ErrAsyncDisabled
+	}
+
+	logger.Info("attempting to queue new person")
+
+	job, err := s.personProvider.CreateJob(ctx, models.Person{
+		Name:             rawData.Name,
+		Surname:          rawData.Surname,
+		Patronymic:       rawData.Patronymic,
+		EnrichmentStatus: models.EnrichmentQueued,
+		PendingAttributes: []string{
+			models.AttributeAge,
+			models.AttributeGender,
+			models.AttributeNationality,
+		},
+		CountryHint: rawData.Country,
+	}, rawData.Country)
+	if err != nil {
+		logger.Debug("can't create job", slog.String("[ERROR]", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !s.queueJob(*job) {
+		// the job is stored as queued and picked up by the next rescan.
+		logger.Warn("job queue is full", slog.Int64("job", job.ID))
+	}
+
+	logger.Info("person queued", slog.Int64("ID", job.PersonID), slog.Int64("job", job.ID))
+
+	return job, nil
+}
+
+// SelectJob returns enrichment job by ID.
+func (s *Service) SelectJob(ctx context.Context, id int64) (*models.Job, error) {
+	const op = "services.people.SelectJob"
+
+	job, err := s.personProvider.SelectJob(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return job, nil
+}
+
+// processJob enriches person of job and saves the result.
+func (s *Service) processJob(ctx context.Context, job models.Job) {
+	const op = "services.people.processJob"
+	logger := s.log.With("operation", op, slog.Int64("job", job.ID))
+
+	if err := s.personProvider.ClaimJob(ctx, job.ID); err != nil {
+		if !errors.Is(err, storage.ErrJobNotQueued) {
+			logger.Warn("can't claim job", slog.String("[ERROR]", err.Error()))
+		}
+		return
+	}
+
+	err := s.enrichQueued(ctx, job)
+	if err != nil && ctx.Err() != nil {
+		// stopped, the job is requeued on the next start.
+		return
+	}
+
+	state, errMsg := models.JobDone, ""
+	if err != nil {
+		logger.Debug("can't enrich person", slog.String("[ERROR]", err.Error()))
+		state, errMsg = models.JobFailed, err.Error()
+
+		if err := s.releaseQueued(ctx, job.PersonID); err != nil {
+			logger.Warn("can't release queued person", slog.String("[ERROR]", err.Error()))
+		}
+	}
+
+	if err := s.personProvider.UpdateJob(ctx, job.ID, state, errMsg); err != nil {
+		logger.Warn("can't update job", slog.String("[ERROR]", err.Error()))
+		return
+	}
+
+	logger.Info("job finished", slog.Int64("ID", job.PersonID), slog.String("state", state))
+}
+
+// releaseQueued makes queued person by ID pending, so the person of a failed job
+// is retried by the pending worker or a re-enrichment run.
+func (s *Service) releaseQueued(ctx context.Context, id int64) error {
+	_, err := s.personProvider.UpdateFields(ctx, id, func(person *models.Person) (map[string]interface{}, error) {
+		if person.EnrichmentStatus != models.EnrichmentQueued {
+			return nil, nil
+		}
+
+		person.EnrichmentStatus = models.EnrichmentPending
+		return map[string]interface{}{"enrichment_status": person.EnrichmentStatus}, nil
+	})
+
+	return err
+}
+
+// enrichQueued enriches queued person of job.
+func (s *Service) enrichQueued(ctx context.Context, job models.Job) error {
+	person, err := s.personProvider.SelectByID(ctx, job.PersonID)
+	if err != nil {
+		return err
+	}
+
+	// all attributes have been set by patch.
+	if len(person.PendingAttributes) == 0 {
+		return nil
+	}
+
+	enriched, err := s.enricher.Process(ctx, models.RawPerson{
+		Name:       person.Name,
+		Surname:    person.Surname,
+		Patronymic: person.Patronymic,
+		Country:    job.Country,
+	})
+	if err != nil {
+		return err
+	}
+
+	// attributes patched while enriching are no longer pending and kept.
+	return s.personProvider.UpdateEnrichment(ctx, person.ID, func(person *models.Person) bool {
+		if len(person.PendingAttributes) == 0 {
+			return false
+		}
+
+		if !fillPending(person, enriched) {
+			person.EnrichmentStatus = models.EnrichmentPending
+			person.Calls = enriched.Calls
+		}
+
+		return true
+	})
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/storage"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// fakeJobStore an in-memory store of jobs, other storage methods aren't implemented.
+type fakeJobStore struct {
+	PersonProvider
+
+	mu       sync.Mutex
+	jobs     []models.Job
+	updates  int
+	statuses map[int64]string
+}
+
+func (f *fakeJobStore) CreateJob(_ context.Context, person models.Person, country string) (*models.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	job := models.Job{ID: int64(len(f.jobs) + 1), PersonID: int64(len(f.jobs) + 1), State: models.JobQueued, Country: country}
+	f.jobs = append(f.jobs, job)
+
+	return &job, nil
+}
+
+func (f *fakeJobStore) SelectQueuedJobs(_ context.Context, limit int) ([]models.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var jobs []models.Job
+	for _, job := range f.jobs {
+		if job.State == models.JobQueued && len(jobs) < limit {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs, nil
+}
+
+func (f *fakeJobStore) ClaimJob(_ context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.jobs[id-1].State != models.JobQueued {
+		return storage.ErrJobNotQueued
+	}
+	f.jobs[id-1].State = models.JobRunning
+
+	return nil
+}
+
+func (f *fakeJobStore) UpdateJob(_ context.Context, id int64, state, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.jobs[id-1].State = state
+
+	return nil
+}
+
+func (f *fakeJobStore) SelectByID(_ context.Context, id int64) (*models.Person, error) {
+	return &models.Person{
+		ID:                id,
+		Name:              "alex",
+		EnrichmentStatus:  models.EnrichmentQueued,
+		PendingAttributes: []string{models.AttributeAge},
+	}, nil
+}
+
+func (f *fakeJobStore) UpdateFields(ctx context.Context, id int64, update func(*models.Person) (map[string]interface{}, error)) (*models.Person, error) {
+	person, _ := f.SelectByID(ctx, id)
+
+	fields, err := update(person)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if status, ok := fields["enrichment_status"]; ok {
+		if f.statuses == nil {
+			f.statuses = make(map[int64]string)
+		}
+		f.statuses[id] = status.(string)
+	}
+
+	return person, nil
+}
+
+func (f *fakeJobStore) UpdateEnrichment(context.Context, int64, func(*models.Person) bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.updates++
+
+	return nil
+}
+
+// fakeEnricher an enricher answering with the same age.
+type fakeEnricher struct {
+	Enricher
+}
+
+func (fakeEnricher) Process(_ context.Context, rawData models.RawPerson) (*models.Person, error) {
+	return &models.Person{Name: rawData.Name, Age: 42, EnrichmentStatus: models.EnrichmentComplete}, nil
+}
+
+func TestEnqueuePersonQueueFull(t *testing.T) {
+	store := &fakeJobStore{}
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, fakeEnricher{})
+	s.jobs = make(chan models.Job, 2)
+
+	for i := 0; i < 5; i++ {
+		job, err := s.EnqueuePerson(context.Background(), models.RawPerson{Name: "alex"})
+		if err != nil || job == nil {
+			t.Fatalf("EnqueuePerson() = %v, %v, want job", job, err)
+		}
+	}
+
+	if len(s.jobs) != 2 {
+		t.Fatalf("queue length = %d, want 2", len(s.jobs))
+	}
+
+	// workers drain the queue, the rescan picks up the rest of stored jobs.
+	for len(s.jobs) > 0 {
+		s.processJob(context.Background(), <-s.jobs)
+	}
+	for i := 0; i < 3; i++ {
+		s.rescanJobs(context.Background())
+		for len(s.jobs) > 0 {
+			s.processJob(context.Background(), <-s.jobs)
+		}
+	}
+
+	for _, job := range store.jobs {
+		if job.State != models.JobDone {
+			t.Errorf("job %d state = %s, want %s", job.ID, job.State, models.JobDone)
+		}
+	}
+	if store.updates != 5 {
+		t.Errorf("persons enriched %d times, want 5", store.updates)
+	}
+}
+
+func TestProcessJobOnce(t *testing.T) {
+	store := &fakeJobStore{}
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, fakeEnricher{})
+	s.jobs = make(chan models.Job, 4)
+
+	job, err := s.EnqueuePerson(context.Background(), models.RawPerson{Name: "alex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rescan queues the same job again before a worker takes it.
+	s.rescanJobs(context.Background())
+	if len(s.jobs) != 2 {
+		t.Fatalf("queue length = %d, want 2", len(s.jobs))
+	}
+
+	for len(s.jobs) > 0 {
+		s.processJob(context.Background(), <-s.jobs)
+	}
+
+	if store.updates != 1 {
+		t.Errorf("job %d processed %d times, want once", job.ID, store.updates)
+	}
+}
+
+// failingEnricher an enricher failing every person.
+type failingEnricher struct {
+	Enricher
+}
+
+func (failingEnricher) Process(context.Context, models.RawPerson) (*models.Person, error) {
+	return nil, errors.New("providers are down")
+}
+
+func TestProcessJobFailureReleasesPerson(t *testing.T) {
+	store := &fakeJobStore{}
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, failingEnricher{})
+	s.jobs = make(chan models.Job, 1)
+
+	job, err := s.EnqueuePerson(context.Background(), models.RawPerson{Name: "alex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.processJob(context.Background(), <-s.jobs)
+
+	if state := store.jobs[job.ID-1].State; state != models.JobFailed {
+		t.Errorf("job state = %s, want %s", state, models.JobFailed)
+	}
+	if status := store.statuses[job.PersonID]; status != models.EnrichmentPending {
+		t.Errorf("person status = %q, want %s", status, models.EnrichmentPending)
+	}
+	if store.updates != 0 {
+		t.Errorf("person enriched %d times, want 0", store.updates)
+	}
+}
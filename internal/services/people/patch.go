@@ -0,0 +1,339 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/models"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")
+	ErrPatchTest    = errors.New("patch test operation failed")
+)
+
+const maxAge = 150
+
+// nullableColumns columns of editable fields which are written as NULL when cleared.
+var nullableColumns = map[string]bool{"age": true, "gender": true, "nationality": true}
+
+// editable fields of person which can be patched, json tags match models.Person
+// and db tags are the columns written by storage.
+type editable struct {
+	Name        string `json:"name" db:"name"`
+	Surname     string `json:"surname" db:"surname"`
+	Patronymic  string `json:"patronymic" db:"patronymic"`
+	Age         uint   `json:"age" db:"age"`
+	Gender      string `json:"gender" db:"gender"`
+	Nationality string `json:"nationality" db:"nationality"`
+}
+
+// MergePatch applies JSON Merge Patch (RFC 7396) to editable fields of person by ID and
+// writes the changed fields only. A null or removed field is cleared.
+func (s *Service) MergePatch(ctx context.Context, id int64, patch map[string]interface{}) (*models.Person, error) {
+	return s.patch(ctx, id, func(doc map[string]interface{}) error {
+		mergePatch(doc, patch)
+		return nil
+	})
+}
+
+// JSONPatch applies JSON Patch (RFC 6902) operations to editable fields of person by ID and
+// writes the changed fields only. Operations are applied to top-level fields, all or none.
+func (s *Service) JSONPatch(ctx context.Context, id int64, ops []models.PatchOperation) (*models.Person, error) {
+	return s.patch(ctx, id, func(doc map[string]interface{}) error {
+		for _, op := range ops {
+			if err := applyOperation(doc, op); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// patch applies patch to document of editable fields of person and writes the changed ones.
+// Patched attributes are no longer pending, so enrichment doesn't overwrite them.
+func (s *Service) patch(ctx context.Context, id int64, apply func(map[string]interface{}) error) (*models.Person, error) {
+	const op = "services.people.patch"
+	logger := s.log.With("operation", op, slog.Int64("ID", id))
+
+	logger.Info("attempting to patch person")
+
+	person, err := s.personProvider.UpdateFields(ctx, id, func(person *models.Person) (map[string]interface{}, error) {
+		current := editable{
+			Name:        person.Name,
+			Surname:     person.Surname,
+			Patronymic:  person.Patronymic,
+			Age:         person.Age,
+			Gender:      person.Gender,
+			Nationality: person.Nationality,
+		}
+
+		doc, err := toDocument(current)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := apply(doc); err != nil {
+			return nil, err
+		}
+
+		patched, err := fromDocument(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		changes := diff(current, patched)
+		if len(changes) == 0 {
+			return nil, nil
+		}
+
+		person.Name = patched.Name
+		person.Surname = patched.Surname
+		person.Patronymic = patched.Patronymic
+		person.Age = patched.Age
+		person.Gender = patched.Gender
+		person.Nationality = patched.Nationality
+		settlePending(person, changes)
+		settleProvenance(person, changes)
+
+		return changes, nil
+	})
+	if err != nil {
+		logger.Debug("can't patch person", slog.String("[ERROR]", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Debug("person successfully patched")
+
+	return person, nil
+}
+
+// settlePending removes attributes written by changes from pending attributes of person
+// and adds the new pending attributes and enrichment status to changes. A queued person
+// stays queued while some of its attributes are pending.
+func settlePending(person *models.Person, changes map[string]interface{}) {
+	pending := slices.DeleteFunc(slices.Clone(person.PendingAttributes), func(attr string) bool {
+		_, ok := changes[attr]
+		return ok
+	})
+	if len(pending) == len(person.PendingAttributes) {
+		return
+	}
+
+	person.PendingAttributes = pending
+	switch {
+	case len(pending) == 0:
+		person.EnrichmentStatus = models.EnrichmentComplete
+	case person.EnrichmentStatus != models.EnrichmentQueued:
+		person.EnrichmentStatus = models.EnrichmentPending
+	}
+
+	changes["pending_attributes"] = person.PendingAttributes
+	changes["enrichment_status"] = person.EnrichmentStatus
+}
+
+// settleProvenance adds provenance of attributes written by changes to changes, a patched
+// attribute comes from SourceManual and a cleared one has no source, both without confidence.
+// Nationality distribution of person is dropped with its enriched nationality.
+func settleProvenance(person *models.Person, changes map[string]interface{}) {
+	source := func(value interface{}) string {
+		if value == nil {
+			return ""
+		}
+		return models.SourceManual
+	}
+
+	if value, ok := changes[models.AttributeAge]; ok {
+		person.AgeCount, person.AgeSource = 0, source(value)
+		changes["age_count"] = person.AgeCount
+		changes["age_source"] = person.AgeSource
+	}
+
+	if value, ok := changes[models.AttributeGender]; ok {
+		person.GenderProbability, person.GenderCount, person.GenderSource = 0, 0, source(value)
+		changes["gender_probability"] = person.GenderProbability
+		changes["gender_count"] = person.GenderCount
+		changes["gender_source"] = person.GenderSource
+	}
+
+	if value, ok := changes[models.AttributeNationality]; ok {
+		person.NationalityProbability, person.NationalityCount, person.NationalitySource = 0, 0, source(value)
+		person.Countries = nil
+		changes["nationality_probability"] = person.NationalityProbability
+		changes["nationality_count"] = person.NationalityCount
+		changes["nationality_source"] = person.NationalitySource
+	}
+}
+
+// toDocument returns JSON document of fields.
+func toDocument(fields editable) (map[string]interface{}, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// fromDocument decodes and validates patched document, missing and null fields are zero.
+func fromDocument(doc map[string]interface{}) (editable, error) {
+	var fields editable
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fields, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&fields); err != nil {
+		return fields, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	fields.Name = strings.TrimSpace(fields.Name)
+	fields.Surname = strings.TrimSpace(fields.Surname)
+	fields.Patronymic = strings.TrimSpace(fields.Patronymic)
+	fields.Gender = strings.ToLower(fields.Gender)
+	fields.Nationality = strings.ToUpper(fields.Nationality)
+
+	switch {
+	case fields.Name == "":
+		return fields, fmt.Errorf("%w: name is required", ErrInvalidPatch)
+	case fields.Surname == "":
+		return fields, fmt.Errorf("%w: surname is required", ErrInvalidPatch)
+	case utf8.RuneCountInString(fields.Name) > models.MaxNameLength:
+		return fields, fmt.Errorf("%w: name must not exceed %d characters", ErrInvalidPatch, models.MaxNameLength)
+	case utf8.RuneCountInString(fields.Surname) > models.MaxNameLength:
+		return fields, fmt.Errorf("%w: surname must not exceed %d characters", ErrInvalidPatch, models.MaxNameLength)
+	case utf8.RuneCountInString(fields.Patronymic) > models.MaxPatronymicLength:
+		return fields, fmt.Errorf("%w: patronymic must not exceed %d characters", ErrInvalidPatch, models.MaxPatronymicLength)
+	case fields.Age > maxAge:
+		return fields, fmt.Errorf("%w: age must not exceed %d", ErrInvalidPatch, maxAge)
+	case fields.Gender != "" && fields.Gender != "male" && fields.Gender != "female":
+		return fields, fmt.Errorf("%w: gender must be male or female", ErrInvalidPatch)
+	case fields.Nationality != "" && !models.IsCountryCode(fields.Nationality):
+		return fields, fmt.Errorf("%w: nationality must be ISO 3166-1 alpha-2 code", ErrInvalidPatch)
+	}
+
+	return fields, nil
+}
+
+// diff returns columns of fields changed from current to patched with their new values,
+// cleared nullable fields are nil.
+func diff(current, patched editable) map[string]interface{} {
+	changes := make(map[string]interface{})
+
+	cur, pat := reflect.ValueOf(current), reflect.ValueOf(patched)
+	for i := 0; i < cur.NumField(); i++ {
+		if cur.Field(i).Interface() == pat.Field(i).Interface() {
+			continue
+		}
+
+		column := cur.Type().Field(i).Tag.Get("db")
+		if pat.Field(i).IsZero() && nullableColumns[column] {
+			changes[column] = nil
+			continue
+		}
+		changes[column] = pat.Field(i).Interface()
+	}
+
+	return changes
+}
+
+// mergePatch applies merge patch to doc as defined by RFC 7396.
+func mergePatch(doc map[string]interface{}, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(doc, key)
+			continue
+		}
+
+		if p, ok := value.(map[string]interface{}); ok {
+			target, ok := doc[key].(map[string]interface{})
+			if !ok {
+				target = make(map[string]interface{})
+			}
+			mergePatch(target, p)
+			doc[key] = target
+			continue
+		}
+
+		doc[key] = value
+	}
+}
+
+// applyOperation applies JSON Patch operation to top-level fields of doc.
+func applyOperation(doc map[string]interface{}, op models.PatchOperation) error {
+	key, err := pointerKey(op.Path)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return fmt.Errorf("%w: %s operation requires value", ErrInvalidPatch, op.Op)
+		}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+		}
+	}
+
+	switch op.Op {
+	case "add", "replace":
+		if _, ok := doc[key]; !ok && op.Op == "replace" {
+			return fmt.Errorf("%w: path %s doesn't exist", ErrInvalidPatch, op.Path)
+		}
+		doc[key] = value
+	case "remove":
+		if _, ok := doc[key]; !ok {
+			return fmt.Errorf("%w: path %s doesn't exist", ErrInvalidPatch, op.Path)
+		}
+		delete(doc, key)
+	case "move", "copy":
+		from, err := pointerKey(op.From)
+		if err != nil {
+			return err
+		}
+		value, ok := doc[from]
+		if !ok {
+			return fmt.Errorf("%w: path %s doesn't exist", ErrInvalidPatch, op.From)
+		}
+		if op.Op == "move" {
+			delete(doc, from)
+		}
+		doc[key] = value
+	case "test":
+		if !reflect.DeepEqual(doc[key], value) {
+			return fmt.Errorf("%w: %s", ErrPatchTest, op.Path)
+		}
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.Op)
+	}
+
+	return nil
+}
+
+// pointerKey returns field name of JSON pointer to a top-level field.
+func pointerKey(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+		return "", fmt.Errorf("%w: path %q must point to a field of person", ErrInvalidPatch, pointer)
+	}
+
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:]), nil
+}
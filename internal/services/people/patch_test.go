@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/binaryty/enricher-service/internal/models"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakePatchStore holds a single person and records the fields written by UpdateFields,
+// other storage methods aren't implemented.
+type fakePatchStore struct {
+	PersonProvider
+
+	person models.Person
+	fields map[string]interface{}
+}
+
+func (f *fakePatchStore) UpdateFields(_ context.Context, _ int64, update func(*models.Person) (map[string]interface{}, error)) (*models.Person, error) {
+	person := f.person
+	person.PendingAttributes = slices.Clone(f.person.PendingAttributes)
+
+	fields, err := update(&person)
+	if err != nil {
+		return nil, err
+	}
+	f.fields = fields
+
+	return &person, nil
+}
+
+func TestPatchSettlesPending(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		pending     []string
+		patch       map[string]interface{}
+		wantStatus  string
+		wantPending []string
+		wantFields  []string
+	}{
+		{
+			name:       "not pending attribute",
+			status:     models.EnrichmentPending,
+			pending:    []string{models.AttributeAge},
+			patch:      map[string]interface{}{"gender": "female"},
+			wantStatus: models.EnrichmentPending, wantPending: []string{models.AttributeAge},
+			wantFields: []string{"gender", "gender_count", "gender_probability", "gender_source"},
+		},
+		{
+			name:       "last pending attribute",
+			status:     models.EnrichmentPending,
+			pending:    []string{models.AttributeAge},
+			patch:      map[string]interface{}{"age": float64(40)},
+			wantStatus: models.EnrichmentComplete, wantPending: []string{},
+			wantFields: []string{"age", "age_count", "age_source", "enrichment_status", "pending_attributes"},
+		},
+		{
+			name:       "queued stays queued",
+			status:     models.EnrichmentQueued,
+			pending:    []string{models.AttributeAge, models.AttributeNationality},
+			patch:      map[string]interface{}{"nationality": "RU"},
+			wantStatus: models.EnrichmentQueued, wantPending: []string{models.AttributeAge},
+			wantFields: []string{"enrichment_status", "nationality", "nationality_count", "nationality_probability",
+				"nationality_source", "pending_attributes"},
+		},
+		{
+			name:       "name only",
+			status:     models.EnrichmentQueued,
+			pending:    []string{models.AttributeGender},
+			patch:      map[string]interface{}{"name": "Anna"},
+			wantStatus: models.EnrichmentQueued, wantPending: []string{models.AttributeGender},
+			wantFields: []string{"name"},
+		},
+		{
+			name:       "nothing changed",
+			status:     models.EnrichmentPending,
+			pending:    []string{models.AttributeGender},
+			patch:      map[string]interface{}{"surname": "Ivanova"},
+			wantStatus: models.EnrichmentPending, wantPending: []string{models.AttributeGender},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakePatchStore{person: models.Person{
+				ID:                1,
+				Name:              "Alla",
+				Surname:           "Ivanova",
+				EnrichmentStatus:  tt.status,
+				PendingAttributes: tt.pending,
+			}}
+			s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil)
+
+			person, err := s.MergePatch(context.Background(), 1, tt.patch)
+			if err != nil {
+				t.Fatalf("MergePatch() error = %v", err)
+			}
+
+			if person.EnrichmentStatus != tt.wantStatus || !reflect.DeepEqual([]string(person.PendingAttributes), tt.wantPending) {
+				t.Errorf("person = %s %v, want %s %v", person.EnrichmentStatus, person.PendingAttributes, tt.wantStatus, tt.wantPending)
+			}
+
+			var fields []string
+			for column := range store.fields {
+				fields = append(fields, column)
+			}
+			slices.Sort(fields)
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("written fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestPatchProvenance(t *testing.T) {
+	enriched := models.Person{
+		ID:                     1,
+		Name:                   "Anna",
+		Surname:                "Ivanova",
+		Age:                    30,
+		AgeCount:               100,
+		AgeSource:              "agify",
+		Gender:                 "female",
+		GenderProbability:      0.98,
+		GenderCount:            100,
+		GenderSource:           "genderize",
+		Nationality:            "UA",
+		NationalityProbability: 0.6,
+		NationalityCount:       100,
+		NationalitySource:      "nationalize",
+		Countries:              []models.Country{{CountryID: "UA", Probability: 0.6}, {CountryID: "RU", Probability: 0.3}},
+		EnrichmentStatus:       models.EnrichmentComplete,
+	}
+
+	tests := []struct {
+		name          string
+		patch         map[string]interface{}
+		want          func(p *models.Person)
+		wantCountries bool
+	}{
+		{
+			name:  "patched age",
+			patch: map[string]interface{}{"age": float64(40)},
+			want: func(p *models.Person) {
+				p.Age, p.AgeCount, p.AgeSource = 40, 0, models.SourceManual
+			},
+			wantCountries: true,
+		},
+		{
+			name:  "cleared gender",
+			patch: map[string]interface{}{"gender": nil},
+			want: func(p *models.Person) {
+				p.Gender, p.GenderProbability, p.GenderCount, p.GenderSource = "", 0, 0, ""
+			},
+			wantCountries: true,
+		},
+		{
+			name:  "patched nationality",
+			patch: map[string]interface{}{"nationality": "pl"},
+			want: func(p *models.Person) {
+				p.Nationality, p.NationalityProbability, p.NationalityCount, p.NationalitySource = "PL", 0, 0, models.SourceManual
+			},
+		},
+		{
+			name:          "same nationality",
+			patch:         map[string]interface{}{"nationality": "UA", "name": "Alla"},
+			want:          func(p *models.Person) { p.Name = "Alla" },
+			wantCountries: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakePatchStore{person: enriched}
+			s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil)
+
+			person, err := s.MergePatch(context.Background(), 1, tt.patch)
+			if err != nil {
+				t.Fatalf("MergePatch() error = %v", err)
+			}
+
+			want := enriched
+			tt.want(&want)
+			if !tt.wantCountries {
+				want.Countries = nil
+			}
+			if !reflect.DeepEqual(*person, want) {
+				t.Errorf("person = %+v, want %+v", *person, want)
+			}
+			if _, ok := store.fields["nationality"]; ok == tt.wantCountries {
+				t.Errorf("nationality written %v, want %v", ok, !tt.wantCountries)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{name: "replace", patch: map[string]interface{}{"age": float64(30)}, want: map[string]interface{}{"name": "Anna", "age": float64(30)}},
+		{name: "null removes", patch: map[string]interface{}{"age": nil}, want: map[string]interface{}{"name": "Anna"}},
+		{name: "add", patch: map[string]interface{}{"gender": "female"}, want: map[string]interface{}{"name": "Anna", "age": float64(20), "gender": "female"}},
+		{name: "empty", patch: map[string]interface{}{}, want: map[string]interface{}{"name": "Anna", "age": float64(20)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := map[string]interface{}{"name": "Anna", "age": float64(20)}
+			mergePatch(doc, tt.patch)
+
+			if !reflect.DeepEqual(doc, tt.want) {
+				t.Errorf("mergePatch() = %v, want %v", doc, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOperation(t *testing.T) {
+	value := func(s string) json.RawMessage { return json.RawMessage(s) }
+
+	tests := []struct {
+		name    string
+		op      models.PatchOperation
+		want    map[string]interface{}
+		wantErr error
+	}{
+		{
+			name: "replace",
+			op:   models.PatchOperation{Op: "replace", Path: "/age", Value: value("30")},
+			want: map[string]interface{}{"name": "Anna", "age": float64(30)},
+		},
+		{
+			name: "replace with null",
+			op:   models.PatchOperation{Op: "replace", Path: "/age", Value: value("null")},
+			want: map[string]interface{}{"name": "Anna", "age": nil},
+		},
+		{
+			name: "add",
+			op:   models.PatchOperation{Op: "add", Path: "/gender", Value: value(`"female"`)},
+			want: map[string]interface{}{"name": "Anna", "age": float64(20), "gender": "female"},
+		},
+		{
+			name: "remove",
+			op:   models.PatchOperation{Op: "remove", Path: "/age"},
+			want: map[string]interface{}{"name": "Anna"},
+		},
+		{
+			name: "copy",
+			op:   models.PatchOperation{Op: "copy", From: "/name", Path: "/surname"},
+			want: map[string]interface{}{"name": "Anna", "surname": "Anna", "age": float64(20)},
+		},
+		{
+			name: "move",
+			op:   models.PatchOperation{Op: "move", From: "/name", Path: "/surname"},
+			want: map[string]interface{}{"surname": "Anna", "age": float64(20)},
+		},
+		{
+			name: "test passes",
+			op:   models.PatchOperation{Op: "test", Path: "/age", Value: value("20")},
+			want: map[string]interface{}{"name": "Anna", "age": float64(20)},
+		},
+		{name: "test fails", op: models.PatchOperation{Op: "test", Path: "/age", Value: value("21")}, wantErr: ErrPatchTest},
+		{name: "replace missing path", op: models.PatchOperation{Op: "replace", Path: "/gender", Value: value(`"male"`)}, wantErr: ErrInvalidPatch},
+		{name: "remove missing path", op: models.PatchOperation{Op: "remove", Path: "/gender"}, wantErr: ErrInvalidPatch},
+		{name: "replace without value", op: models.PatchOperation{Op: "replace", Path: "/age"}, wantErr: ErrInvalidPatch},
+		{name: "add without value", op: models.PatchOperation{Op: "add", Path: "/gender"}, wantErr: ErrInvalidPatch},
+		{name: "test without value", op: models.PatchOperation{Op: "test", Path: "/age"}, wantErr: ErrInvalidPatch},
+		{name: "nested path", op: models.PatchOperation{Op: "remove", Path: "/countries/0"}, wantErr: ErrInvalidPatch},
+		{name: "unknown op", op: models.PatchOperation{Op: "merge", Path: "/age"}, wantErr: ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := map[string]interface{}{"name": "Anna", "age": float64(20)}
+
+			err := applyOperation(doc, tt.op)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("applyOperation() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && !reflect.DeepEqual(doc, tt.want) {
+				t.Errorf("applyOperation() = %v, want %v", doc, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromDocument(t *testing.T) {
+	long := func(n int) string { return strings.Repeat("я", n) }
+
+	tests := []struct {
+		name    string
+		doc     map[string]interface{}
+		want    editable
+		wantErr bool
+	}{
+		{
+			name: "normalized",
+			doc:  map[string]interface{}{"name": " Anna ", "surname": "Ivanova", "gender": "Female", "nationality": "ru", "age": float64(150)},
+			want: editable{Name: "Anna", Surname: "Ivanova", Gender: "female", Nationality: "RU", Age: 150},
+		},
+		{
+			name: "cleared fields",
+			doc:  map[string]interface{}{"name": "Anna", "surname": "Ivanova", "age": nil},
+			want: editable{Name: "Anna", Surname: "Ivanova"},
+		},
+		{
+			name: "longest names",
+			doc:  map[string]interface{}{"name": long(100), "surname": long(100), "patronymic": long(200)},
+			want: editable{Name: long(100), Surname: long(100), Patronymic: long(200)},
+		},
+		{name: "no name", doc: map[string]interface{}{"surname": "Ivanova"}, wantErr: true},
+		{name: "blank surname", doc: map[string]interface{}{"name": "Anna", "surname": "  "}, wantErr: true},
+		{name: "long name", doc: map[string]interface{}{"name": long(101), "surname": "Ivanova"}, wantErr: true},
+		{name: "long surname", doc: map[string]interface{}{"name": "Anna", "surname": long(101)}, wantErr: true},
+		{name: "long patronymic", doc: map[string]interface{}{"name": "Anna", "surname": "Ivanova", "patronymic": long(201)}, wantErr: true},
+		{name: "too old", doc: map[string]interface{}{"name": "Anna", "surname": "Ivanova", "age": float64(151)}, wantErr: true},
+		{name: "negative age", doc: map[string]interface{}{"name": "Anna", "surname": "Ivanova", "age": float64(-1)}, wantErr: true},
+		{name: "fractional age", doc: map[string]interface{}{"name": "Anna", "surname": "Ivanova", "age": 20.5}, wantErr: true},
+		{name: "unknown gender", doc: map[string]interface{}{"name": "Anna", "surname": "Ivanova", "gender": "other"}, wantErr: true},
+		{name: "bad nationality", doc: map[string]interface{}{"name": "Anna", "surname": "Ivanova", "nationality": "RUS"}, wantErr: true},
+		{name: "unknown field", doc: map[string]interface{}{"name": "Anna", "surname": "Ivanova", "id": float64(2)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fromDocument(tt.doc)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPatch) {
+					t.Errorf("fromDocument() error = %v, want ErrInvalidPatch", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("fromDocument() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("fromDocument() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := editable{Name: "Anna", Surname: "Ivanova", Patronymic: "Petrovna", Age: 30, Gender: "female", Nationality: "RU"}
+
+	tests := []struct {
+		name    string
+		patched editable
+		want    map[string]interface{}
+	}{
+		{name: "unchanged", patched: current, want: map[string]interface{}{}},
+		{
+			name:    "changed",
+			patched: editable{Name: "Anna", Surname: "Petrova", Patronymic: "Petrovna", Age: 31, Gender: "female", Nationality: "RU"},
+			want:    map[string]interface{}{"surname": "Petrova", "age": uint(31)},
+		},
+		{
+			name:    "cleared",
+			patched: editable{Name: "Anna", Surname: "Ivanova"},
+			want:    map[string]interface{}{"patronymic": "", "age": nil, "gender": nil, "nationality": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diff(current, tt.patched); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
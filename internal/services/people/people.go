@@ -7,16 +7,31 @@ import (
 	"github.com/binaryty/enricher-service/internal/models"
 	"github.com/mattn/go-sqlite3"
 	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBulkConcurrency max number of persons of a bulk enriched at once.
+	DefaultBulkConcurrency = 8
+	// DefaultRunHeartbeat interval of heartbeats of executed re-enrichment runs.
+	DefaultRunHeartbeat = 30 * time.Second
 )
 
 type Service struct {
-	log            *slog.Logger
-	personProvider PersonProvider
-	enricher       Enricher
+	log             *slog.Logger
+	personProvider  PersonProvider
+	enricher        Enricher
+	bulkConcurrency int
+	runHeartbeat    time.Duration
+	jobs            chan models.Job
+	runs            chan *models.ReenrichRun
 }
 
 type Enricher interface {
 	Process(context.Context, models.RawPerson) (*models.Person, error)
+	ProcessAttributes(context.Context, models.RawPerson, []string) (*models.Person, error)
 }
 
 type PersonProvider interface {
@@ -24,20 +39,71 @@ type PersonProvider interface {
 	SelectByID(context.Context, int64) (*models.Person, error)
 	Update(context.Context, *models.Person) error
 	SelectAll(context.Context, models.Params) ([]models.Person, error)
+	Count(context.Context, models.Params) (int64, error)
+	Search(ctx context.Context, q string, limit int) ([]models.SearchResult, error)
+	UpdateFields(ctx context.Context, id int64, update func(*models.Person) (map[string]interface{}, error)) (*models.Person, error)
 	DeleteByID(context.Context, int64) error
+	SelectPending(context.Context, int) ([]models.Person, error)
+	DeferPending(ctx context.Context, id int64, backoff time.Duration, maxAttempts int) error
+	UpdateEnrichment(ctx context.Context, id int64, fill func(*models.Person) bool) error
+	SelectEnrichmentLog(context.Context, int64) ([]models.EnrichmentCall, error)
+	CreateJob(context.Context, models.Person, string) (*models.Job, error)
+	SelectJob(context.Context, int64) (*models.Job, error)
+	SelectQueuedJobs(context.Context, int) ([]models.Job, error)
+	RequeueRunningJobs(context.Context) (int64, error)
+	ClaimJob(context.Context, int64) error
+	UpdateJob(context.Context, int64, string, string) error
+	CreateReenrichRun(context.Context, models.ReenrichParams) (*models.ReenrichRun, error)
+	SelectReenrichRun(context.Context, int64) (*models.ReenrichRun, error)
+	SelectQueuedReenrichRuns(context.Context) ([]models.ReenrichRun, error)
+	HeartbeatReenrichRun(context.Context, int64) error
+	SaveReenrichProgress(context.Context, *models.ReenrichRun) error
+	StopStaleReenrichRuns(ctx context.Context, staleAfter time.Duration, reason string) ([]int64, error)
+	UpdateReenrichRun(context.Context, *models.ReenrichRun) error
+	SelectForReenrich(context.Context, models.ReenrichParams, int64, int) ([]models.Person, error)
 }
 
 var (
 	ErrPersonNotFound = errors.New("person not found")
 )
 
+// Option configures Service.
+type Option func(s *Service)
+
+// WithBulkConcurrency sets max number of persons of a bulk enriched at once, DefaultBulkConcurrency by default.
+func WithBulkConcurrency(n int) Option {
+	return func(s *Service) {
+		if n > 0 {
+			s.bulkConcurrency = n
+		}
+	}
+}
+
+// WithRunHeartbeat sets interval of heartbeats of executed re-enrichment runs, DefaultRunHeartbeat by default.
+// A running run without heartbeat for three intervals is considered interrupted.
+func WithRunHeartbeat(d time.Duration) Option {
+	return func(s *Service) {
+		if d > 0 {
+			s.runHeartbeat = d
+		}
+	}
+}
+
 // New returns a new instance of People service.
-func New(log *slog.Logger, personProvider PersonProvider, enricher Enricher) *Service {
-	return &Service{
-		log:            log,
-		personProvider: personProvider,
-		enricher:       enricher,
+func New(log *slog.Logger, personProvider PersonProvider, enricher Enricher, opts ...Option) *Service {
+	s := &Service{
+		log:             log,
+		personProvider:  personProvider,
+		enricher:        enricher,
+		bulkConcurrency: DefaultBulkConcurrency,
+		runHeartbeat:    DefaultRunHeartbeat,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
 // AddPerson enrich raw person data and save to storage.
@@ -55,12 +121,25 @@ func (s *Service) AddPerson(ctx context.Context, rawData models.RawPerson) (int6
 	}
 
 	person := models.Person{
-		Name:        rawData.Name,
-		Surname:     rawData.Surname,
-		Patronymic:  rawData.Patronymic,
-		Age:         enrichResponse.Age,
-		Gender:      enrichResponse.Gender,
-		Nationality: enrichResponse.Nationality,
+		Name:                   rawData.Name,
+		Surname:                rawData.Surname,
+		Patronymic:             rawData.Patronymic,
+		Age:                    enrichResponse.Age,
+		AgeCount:               enrichResponse.AgeCount,
+		AgeSource:              enrichResponse.AgeSource,
+		Gender:                 enrichResponse.Gender,
+		GenderProbability:      enrichResponse.GenderProbability,
+		GenderCount:            enrichResponse.GenderCount,
+		GenderSource:           enrichResponse.GenderSource,
+		Nationality:            enrichResponse.Nationality,
+		NationalityProbability: enrichResponse.NationalityProbability,
+		NationalityCount:       enrichResponse.NationalityCount,
+		NationalitySource:      enrichResponse.NationalitySource,
+		Countries:              enrichResponse.Countries,
+		EnrichmentStatus:       enrichResponse.EnrichmentStatus,
+		PendingAttributes:      enrichResponse.PendingAttributes,
+		CountryHint:            rawData.Country,
+		Calls:                  enrichResponse.Calls,
 	}
 
 	logger.Info("attempting to add new person in storage")
@@ -72,11 +151,89 @@ func (s *Service) AddPerson(ctx context.Context, rawData models.RawPerson) (int6
 		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if person.EnrichmentStatus == models.EnrichmentPending {
+		logger.Info("person enrichment is pending", slog.Int64("ID", id),
+			slog.Any("attributes", []string(person.PendingAttributes)))
+	}
+
 	logger.Info("person successfully created", slog.Int64("ID", id))
 
 	return id, nil
 }
 
+// AddPersons enrich a bulk of raw persons concurrently and save them to storage.
+// It returns ids and errors in the order of rawData, id is -1 for the failed persons.
+func (s *Service) AddPersons(ctx context.Context, rawData []models.RawPerson) ([]int64, []error) {
+	const op = "services.people.AddPersons"
+	logger := s.log.With("operation", op)
+
+	logger.Info("attempting to add persons", slog.Int("count", len(rawData)))
+
+	ids := make([]int64, len(rawData))
+	errs := make([]error, len(rawData))
+
+	s.forEach(len(rawData), func(i int) {
+		ids[i], errs[i] = s.AddPerson(ctx, rawData[i])
+	})
+
+	return ids, errs
+}
+
+// Enrich enriches raw person data without saving it.
+func (s *Service) Enrich(ctx context.Context, rawData models.RawPerson) (*models.Person, error) {
+	const op = "services.people.Enrich"
+	logger := s.log.With("operation", op)
+
+	logger.Info("attempting to preview person enrichment")
+
+	person, err := s.enricher.Process(ctx, rawData)
+	if err != nil {
+		logger.Warn("can't enriched person", slog.String("[ERROR]", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	person.EnrichedAt = time.Now()
+
+	return person, nil
+}
+
+// EnrichAll enriches a list of raw persons concurrently without saving them.
+// It returns persons and errors in the order of rawData.
+func (s *Service) EnrichAll(ctx context.Context, rawData []models.RawPerson) ([]*models.Person, []error) {
+	persons := make([]*models.Person, len(rawData))
+	errs := make([]error, len(rawData))
+
+	s.forEach(len(rawData), func(i int) {
+		persons[i], errs[i] = s.Enrich(ctx, rawData[i])
+	})
+
+	return persons, errs
+}
+
+// forEach calls fn for indexes from 0 to n-1 by at most bulkConcurrency goroutines.
+func (s *Service) forEach(n int, fn func(i int)) {
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.bulkConcurrency, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+}
+
 // SelectByID ...
 func (s *Service) SelectByID(ctx context.Context, id int64) (*models.Person, error) {
 	const op = "services.people.SelectByID"
@@ -97,8 +254,25 @@ func (s *Service) SelectByID(ctx context.Context, id int64) (*models.Person, err
 	return person, nil
 }
 
-// SelectAll get all persons from storage according parameters.
-func (s *Service) SelectAll(ctx context.Context, params models.Params) ([]models.Person, error) {
+// SelectEnrichmentLog returns provider calls made to enrich person.
+func (s *Service) SelectEnrichmentLog(ctx context.Context, id int64) ([]models.EnrichmentCall, error) {
+	const op = "services.people.SelectEnrichmentLog"
+	logger := s.log.With("operation", op)
+
+	logger.Info("attempting to select enrichment log")
+
+	calls, err := s.personProvider.SelectEnrichmentLog(ctx, id)
+	if err != nil {
+		logger.Debug("can't select enrichment log", slog.String("[ERROR]", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return calls, nil
+}
+
+// SelectAll get persons from storage according parameters and total number of persons matching filters.
+func (s *Service) SelectAll(ctx context.Context, params models.Params) ([]models.Person, int64, error) {
 	const op = "services.people.SelectAll"
 	logger := s.log.With("operation", op)
 
@@ -109,18 +283,60 @@ func (s *Service) SelectAll(ctx context.Context, params models.Params) ([]models
 		if errors.Is(err, ErrPersonNotFound) {
 			logger.Debug("not found", slog.String("[ERROR]", err.Error()))
 
-			return nil, ErrPersonNotFound
+			return nil, 0, ErrPersonNotFound
 		}
 		logger.Debug("can't query persons", slog.String("[ERROR]", err.Error()))
 
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	total, err := s.personProvider.Count(ctx, params)
+	if err != nil {
+		logger.Debug("can't count persons", slog.String("[ERROR]", err.Error()))
+
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
 	}
 
 	logger.Debug("all persons get successfully")
 
+	return persons, total, nil
+}
+
+// SelectByKeyset get persons from storage according parameters without counting them,
+// it is used by cursor pagination.
+func (s *Service) SelectByKeyset(ctx context.Context, params models.Params) ([]models.Person, error) {
+	const op = "services.people.SelectByKeyset"
+	logger := s.log.With("operation", op)
+
+	logger.Info("attempting to query persons by keyset")
+
+	persons, err := s.personProvider.SelectAll(ctx, params)
+	if err != nil {
+		logger.Debug("can't query persons", slog.String("[ERROR]", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return persons, nil
 }
 
+// Search get at most limit persons with names similar to q, ranked by similarity.
+func (s *Service) Search(ctx context.Context, q string, limit int) ([]models.SearchResult, error) {
+	const op = "services.people.Search"
+	logger := s.log.With("operation", op)
+
+	logger.Info("attempting to search persons", slog.String("query", q))
+
+	results, err := s.personProvider.Search(ctx, q, limit)
+	if err != nil {
+		logger.Debug("can't search persons", slog.String("[ERROR]", err.Error()))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return results, nil
+}
+
 // DeleteByID delete person from storage by ID.
 func (s *Service) DeleteByID(ctx context.Context, id int64) error {
 	const op = "services.person.DeleteByID"
@@ -161,3 +377,138 @@ func (s *Service) Update(ctx context.Context, params *models.Person) error {
 
 	return nil
 }
+
+// RunPendingWorker periodically enriches pending attributes of persons until ctx is done,
+// a person is retried after interval doubled by every failed attempt and marked failed
+// after maxAttempts failed attempts.
+func (s *Service) RunPendingWorker(ctx context.Context, interval time.Duration, batchSize, maxAttempts int) {
+	const op = "services.people.RunPendingWorker"
+	logger := s.log.With("operation", op)
+
+	logger.Info("pending enrichment worker started", slog.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("pending enrichment worker stopped")
+			return
+		case <-ticker.C:
+			if err := s.ProcessPending(ctx, batchSize, interval, maxAttempts); err != nil {
+				logger.Warn("can't process pending persons", slog.String("[ERROR]", err.Error()))
+			}
+		}
+	}
+}
+
+// ProcessPending enriches pending attributes of a batch of persons whose attempt is due,
+// persons which can't be enriched are deferred by backoff, see RunPendingWorker.
+func (s *Service) ProcessPending(ctx context.Context, batchSize int, backoff time.Duration, maxAttempts int) error {
+	const op = "services.people.ProcessPending"
+	logger := s.log.With("operation", op)
+
+	persons, err := s.personProvider.SelectPending(ctx, batchSize)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Debug("attempting to enrich pending persons", slog.Int("count", len(persons)))
+
+	for i := range persons {
+		person := &persons[i]
+
+		enriched, err := s.enricher.ProcessAttributes(ctx, models.RawPerson{
+			Name:       person.Name,
+			Surname:    person.Surname,
+			Patronymic: person.Patronymic,
+			Country:    person.CountryHint,
+		}, person.PendingAttributes)
+		if err != nil {
+			logger.Debug("can't enrich person", slog.Int64("ID", person.ID), slog.String("[ERROR]", err.Error()))
+			s.deferPending(ctx, person.ID, backoff, maxAttempts)
+			continue
+		}
+
+		// attributes patched while enriching are no longer pending and kept.
+		var (
+			filled, settled bool
+			status          string
+		)
+		err = s.personProvider.UpdateEnrichment(ctx, person.ID, func(person *models.Person) bool {
+			settled = len(person.PendingAttributes) == 0
+			filled = fillPending(person, enriched)
+			status = person.EnrichmentStatus
+			return filled
+		})
+		if err != nil {
+			logger.Debug("can't update person", slog.Int64("ID", person.ID), slog.String("[ERROR]", err.Error()))
+			continue
+		}
+
+		if !filled {
+			if !settled {
+				s.deferPending(ctx, person.ID, backoff, maxAttempts)
+			}
+			continue
+		}
+
+		logger.Info("pending person enriched", slog.Int64("ID", person.ID), slog.String("status", status))
+	}
+
+	return nil
+}
+
+// deferPending records a failed enrichment attempt of person by ID.
+func (s *Service) deferPending(ctx context.Context, id int64, backoff time.Duration, maxAttempts int) {
+	if err := s.personProvider.DeferPending(ctx, id, backoff, maxAttempts); err != nil {
+		s.log.Warn("can't defer pending person", slog.Int64("ID", id), slog.String("[ERROR]", err.Error()))
+	}
+}
+
+// fillPending copies newly enriched attributes into person and reports whether any was filled.
+func fillPending(person *models.Person, enriched *models.Person) bool {
+	pending := make([]string, 0, len(person.PendingAttributes))
+
+	for _, attr := range person.PendingAttributes {
+		if slices.Contains(enriched.PendingAttributes, attr) {
+			pending = append(pending, attr)
+			continue
+		}
+
+		switch attr {
+		case models.AttributeAge:
+			person.Age = enriched.Age
+			person.AgeCount = enriched.AgeCount
+			person.AgeSource = enriched.AgeSource
+		case models.AttributeGender:
+			person.Gender = enriched.Gender
+			person.GenderProbability = enriched.GenderProbability
+			person.GenderCount = enriched.GenderCount
+			person.GenderSource = enriched.GenderSource
+		case models.AttributeNationality:
+			person.Nationality = enriched.Nationality
+			person.NationalityProbability = enriched.NationalityProbability
+			person.NationalityCount = enriched.NationalityCount
+			person.NationalitySource = enriched.NationalitySource
+			person.Countries = enriched.Countries
+		default:
+			pending = append(pending, attr)
+		}
+	}
+
+	if len(pending) == len(person.PendingAttributes) {
+		return false
+	}
+
+	person.Calls = enriched.Calls
+
+	person.PendingAttributes = pending
+	person.EnrichmentStatus = models.EnrichmentPending
+	if len(pending) == 0 {
+		person.EnrichmentStatus = models.EnrichmentComplete
+	}
+
+	return true
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"github.com/binaryty/enricher-service/internal/models"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakePendingStore an in-memory store of pending persons following the storage semantics
+// of attempts, other storage methods aren't implemented.
+type fakePendingStore struct {
+	PersonProvider
+
+	now      time.Time
+	persons  []models.Person
+	attempts map[int64]int
+	nextAt   map[int64]time.Time
+}
+
+func (f *fakePendingStore) SelectPending(_ context.Context, limit int) ([]models.Person, error) {
+	var persons []models.Person
+	for _, p := range f.persons {
+		if p.EnrichmentStatus == models.EnrichmentPending && !f.nextAt[p.ID].After(f.now) {
+			persons = append(persons, p)
+		}
+	}
+
+	slices.SortStableFunc(persons, func(a, b models.Person) int {
+		return f.nextAt[a.ID].Compare(f.nextAt[b.ID])
+	})
+
+	return persons[:min(limit, len(persons))], nil
+}
+
+func (f *fakePendingStore) DeferPending(_ context.Context, id int64, backoff time.Duration, maxAttempts int) error {
+	f.nextAt[id] = f.now.Add(backoff << f.attempts[id])
+	f.attempts[id]++
+	if f.attempts[id] >= maxAttempts {
+		f.persons[id-1].EnrichmentStatus = models.EnrichmentFailed
+	}
+
+	return nil
+}
+
+func (f *fakePendingStore) SelectByID(_ context.Context, id int64) (*models.Person, error) {
+	person := f.persons[id-1]
+	person.PendingAttributes = slices.Clone(person.PendingAttributes)
+
+	return &person, nil
+}
+
+func (f *fakePendingStore) UpdateEnrichment(ctx context.Context, id int64, fill func(*models.Person) bool) error {
+	person, _ := f.SelectByID(ctx, id)
+	if !fill(person) {
+		return nil
+	}
+
+	f.persons[id-1] = *person
+	f.attempts[id] = 0
+	f.nextAt[id] = f.now
+
+	return nil
+}
+
+// fakePendingEnricher an enricher knowing every name except "zzz".
+type fakePendingEnricher struct {
+	Enricher
+}
+
+func (fakePendingEnricher) ProcessAttributes(_ context.Context, rawData models.RawPerson, attrs []string) (*models.Person, error) {
+	if rawData.Name == "zzz" {
+		return &models.Person{PendingAttributes: attrs, EnrichmentStatus: models.EnrichmentPending}, nil
+	}
+
+	return &models.Person{Age: 42, AgeSource: "agify", EnrichmentStatus: models.EnrichmentComplete}, nil
+}
+
+func TestProcessPending(t *testing.T) {
+	const (
+		batchSize   = 4
+		maxAttempts = 3
+		backoff     = time.Minute
+	)
+
+	// unknown names come first and fill a whole batch.
+	store := &fakePendingStore{now: time.Now(), attempts: map[int64]int{}, nextAt: map[int64]time.Time{}}
+	for i := 1; i <= 6; i++ {
+		name := "zzz"
+		if i > 4 {
+			name = "anna"
+		}
+		store.persons = append(store.persons, models.Person{
+			ID:                int64(i),
+			Name:              name,
+			EnrichmentStatus:  models.EnrichmentPending,
+			PendingAttributes: []string{models.AttributeAge},
+		})
+	}
+
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, fakePendingEnricher{})
+
+	// the worker ticks every backoff.
+	for tick := 0; tick < 8; tick++ {
+		if err := s.ProcessPending(context.Background(), batchSize, backoff, maxAttempts); err != nil {
+			t.Fatalf("ProcessPending() error = %v", err)
+		}
+		store.now = store.now.Add(backoff)
+
+		if tick == 1 {
+			for _, p := range store.persons[4:] {
+				if p.EnrichmentStatus != models.EnrichmentComplete {
+					t.Fatalf("person %d is %s after two ticks, want %s", p.ID, p.EnrichmentStatus, models.EnrichmentComplete)
+				}
+			}
+		}
+	}
+
+	for _, p := range store.persons[:4] {
+		if p.EnrichmentStatus != models.EnrichmentFailed || store.attempts[p.ID] != maxAttempts {
+			t.Errorf("person %d is %s after %d attempts, want %s after %d", p.ID, p.EnrichmentStatus,
+				store.attempts[p.ID], models.EnrichmentFailed, maxAttempts)
+		}
+	}
+}
+
+// hintEnricher an enricher recording country hints of the persons it enriches.
+type hintEnricher struct {
+	Enricher
+
+	countries []string
+}
+
+func (e *hintEnricher) Process(ctx context.Context, rawData models.RawPerson) (*models.Person, error) {
+	return e.ProcessAttributes(ctx, rawData, nil)
+}
+
+func (e *hintEnricher) ProcessAttributes(_ context.Context, rawData models.RawPerson, _ []string) (*models.Person, error) {
+	e.countries = append(e.countries, rawData.Country)
+
+	return &models.Person{Age: 42, AgeSource: "agify", EnrichmentStatus: models.EnrichmentComplete}, nil
+}
+
+func TestCountryHintIsUsed(t *testing.T) {
+	person := models.Person{
+		ID:                1,
+		Name:              "anna",
+		EnrichmentStatus:  models.EnrichmentPending,
+		PendingAttributes: []string{models.AttributeAge},
+		CountryHint:       "UA",
+	}
+
+	tests := []struct {
+		name   string
+		enrich func(s *Service) error
+	}{
+		{
+			name: "pending worker",
+			enrich: func(s *Service) error {
+				return s.ProcessPending(context.Background(), 10, time.Minute, 3)
+			},
+		},
+		{
+			name: "re-enrichment",
+			enrich: func(s *Service) error {
+				p := person
+				return s.reenrichPerson(context.Background(), &models.ReenrichRun{}, &p)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakePendingStore{
+				now:      time.Now(),
+				persons:  []models.Person{person},
+				attempts: map[int64]int{},
+				nextAt:   map[int64]time.Time{},
+			}
+			e := &hintEnricher{}
+			s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, e)
+
+			if err := tt.enrich(s); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+
+			if !slices.Equal(e.countries, []string{"UA"}) {
+				t.Errorf("enriched with countries %v, want [UA]", e.countries)
+			}
+		})
+	}
+}
+
+// patchingEnricher an enricher patching age of the person it enriches as a concurrent PATCH does.
+type patchingEnricher struct {
+	Enricher
+
+	store *fakePendingStore
+}
+
+func (e *patchingEnricher) Process(ctx context.Context, rawData models.RawPerson) (*models.Person, error) {
+	return e.ProcessAttributes(ctx, rawData, nil)
+}
+
+func (e *patchingEnricher) ProcessAttributes(context.Context, models.RawPerson, []string) (*models.Person, error) {
+	person := &e.store.persons[0]
+	person.Age = 99
+	person.PendingAttributes = []string{models.AttributeGender}
+
+	return &models.Person{
+		Age:               42,
+		AgeSource:         "agify",
+		Gender:            "female",
+		GenderProbability: 0.98,
+		GenderSource:      "genderize",
+		EnrichmentStatus:  models.EnrichmentComplete,
+	}, nil
+}
+
+func TestEnrichmentKeepsPatchedAttributes(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		enrich func(s *Service) error
+	}{
+		{
+			name:   "pending worker",
+			status: models.EnrichmentPending,
+			enrich: func(s *Service) error {
+				return s.ProcessPending(context.Background(), 10, time.Minute, 3)
+			},
+		},
+		{
+			name:   "job",
+			status: models.EnrichmentQueued,
+			enrich: func(s *Service) error {
+				return s.enrichQueued(context.Background(), models.Job{ID: 1, PersonID: 1})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakePendingStore{
+				now: time.Now(),
+				persons: []models.Person{{
+					ID:                1,
+					Name:              "anna",
+					EnrichmentStatus:  tt.status,
+					PendingAttributes: []string{models.AttributeAge, models.AttributeGender},
+				}},
+				attempts: map[int64]int{},
+				nextAt:   map[int64]time.Time{},
+			}
+			s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, &patchingEnricher{store: store})
+
+			if err := tt.enrich(s); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+
+			got := store.persons[0]
+			if got.Age != 99 || got.AgeSource != "" {
+				t.Errorf("patched age = %d from %q, want 99 kept", got.Age, got.AgeSource)
+			}
+			if got.Gender != "female" || got.EnrichmentStatus != models.EnrichmentComplete || len(got.PendingAttributes) != 0 {
+				t.Errorf("person = %s %s pending %v, want female complete", got.Gender, got.EnrichmentStatus, got.PendingAttributes)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/storage"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+var (
+	ErrRunFinished    = errors.New("re-enrichment run is already done")
+	ErrRunActive      = errors.New("re-enrichment run is already queued or running")
+	ErrReenrichBusy   = errors.New("too many re-enrichment runs queued")
+	ErrRunInterrupted = errors.New("re-enrichment run was interrupted, its executor stopped sending heartbeats")
+	ErrRunLost        = errors.New("re-enrichment run was stopped while being executed")
+)
+
+// staleHeartbeats number of missed heartbeats after which a running run is considered interrupted.
+const staleHeartbeats = 3
+
+// StartReenrichWorker starts worker executing queued re-enrichment runs one by one until ctx is done,
+// requeues runs left queued by the previous run and stops interrupted runs.
+func (s *Service) StartReenrichWorker(ctx context.Context, batchSize int) {
+	const op = "services.people.StartReenrichWorker"
+	logger := s.log.With("operation", op)
+
+	s.runs = make(chan *models.ReenrichRun, 16)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case run := <-s.runs:
+				if err := s.RunReenrich(ctx, run, batchSize); err != nil {
+					logger.Warn("re-enrichment run failed", slog.Int64("run", run.ID), slog.String("[ERROR]", err.Error()))
+				}
+			}
+		}
+	}()
+
+	go s.recoverRuns(ctx)
+
+	go func() {
+		ticker := time.NewTicker(s.runHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			s.stopStaleRuns(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// recoverRuns requeues queued runs.
+func (s *Service) recoverRuns(ctx context.Context) {
+	const op = "services.people.recoverRuns"
+	logger := s.log.With("operation", op)
+
+	runs, err := s.personProvider.SelectQueuedReenrichRuns(ctx)
+	if err != nil {
+		logger.Warn("can't recover queued re-enrichment runs", slog.String("[ERROR]", err.Error()))
+		return
+	}
+
+	for i := range runs {
+		if err := s.queueRun(&runs[i]); err != nil {
+			logger.Warn("can't requeue re-enrichment run", slog.Int64("run", runs[i].ID), slog.String("[ERROR]", err.Error()))
+		}
+	}
+}
+
+// stopStaleRuns stops running runs whose executor, the service or the reenrich command,
+// has stopped sending heartbeats, so they can be resumed. Runs being executed are left alone.
+func (s *Service) stopStaleRuns(ctx context.Context) {
+	const op = "services.people.stopStaleRuns"
+	logger := s.log.With("operation", op)
+
+	ids, err := s.personProvider.StopStaleReenrichRuns(ctx, staleHeartbeats*s.runHeartbeat, ErrRunInterrupted.Error())
+	if err != nil {
+		logger.Warn("can't stop interrupted re-enrichment runs", slog.String("[ERROR]", err.Error()))
+		return
+	}
+
+	for _, id := range ids {
+		logger.Info("interrupted re-enrichment run stopped", slog.Int64("run", id))
+	}
+}
+
+// heartbeat sends heartbeats of run until ctx is done, lost is called
+// if the run has been stopped by someone else in the meantime.
+func (s *Service) heartbeat(ctx context.Context, run *models.ReenrichRun, lost func()) {
+	ticker := time.NewTicker(s.runHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.personProvider.HeartbeatReenrichRun(ctx, run.ID)
+		if errors.Is(err, storage.ErrRunNotRunning) {
+			lost()
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			s.log.Warn("can't send heartbeat of re-enrichment run", slog.Int64("run", run.ID), slog.String("[ERROR]", err.Error()))
+		}
+	}
+}
+
+// StartReenrich creates a re-enrichment run of persons matching params and queues it.
+func (s *Service) StartReenrich(ctx context.Context, params models.ReenrichParams) (*models.ReenrichRun, error) {
+	const op = "services.people.StartReenrich"
+
+	run, err := s.personProvider.CreateReenrichRun(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.queueRun(run); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return run, nil
+}
+
+// ResumeReenrich queues a stopped or failed re-enrichment run to continue from its last person.
+func (s *Service) ResumeReenrich(ctx context.Context, id int64) (*models.ReenrichRun, error) {
+	const op = "services.people.ResumeReenrich"
+
+	run, err := s.personProvider.SelectReenrichRun(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := CheckResumable(run); err != nil {
+		return nil, err
+	}
+
+	run.State = models.RunQueued
+	run.Error = ""
+	if err := s.personProvider.UpdateReenrichRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.queueRun(run); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return run, nil
+}
+
+// CheckResumable returns error if run is done or is queued or running already.
+func CheckResumable(run *models.ReenrichRun) error {
+	switch run.State {
+	case models.RunDone:
+		return ErrRunFinished
+	case models.RunQueued, models.RunRunning:
+		return ErrRunActive
+	}
+
+	return nil
+}
+
+// SelectReenrichRun returns re-enrichment run by ID.
+func (s *Service) SelectReenrichRun(ctx context.Context, id int64) (*models.ReenrichRun, error) {
+	const op = "services.people.SelectReenrichRun"
+
+	run, err := s.personProvider.SelectReenrichRun(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return run, nil
+}
+
+// queueRun hands run to the worker, the run is marked stopped if the worker is busy.
+func (s *Service) queueRun(run *models.ReenrichRun) error {
+	select {
+	case s.runs <- run:
+		return nil
+	default:
+		run.State = models.RunStopped
+		run.Error = ErrReenrichBusy.Error()
+		_ = s.personProvider.UpdateReenrichRun(context.Background(), run)
+
+		return ErrReenrichBusy
+	}
+}
+
+// RunReenrich re-enriches persons matching run params in batches of batchSize
+// at most run.Rate persons per second, progress is saved after every person.
+func (s *Service) RunReenrich(ctx context.Context, run *models.ReenrichRun, batchSize int) error {
+	const op = "services.people.RunReenrich"
+	logger := s.log.With("operation", op, slog.Int64("run", run.ID))
+
+	// progress must be saved even when ctx is done.
+	saveCtx := context.WithoutCancel(ctx)
+
+	run.State = models.RunRunning
+	if err := s.personProvider.UpdateReenrichRun(saveCtx, run); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("re-enrichment run started", slog.Int64("after", run.LastID))
+
+	// the run is abandoned at once if it is stopped by someone else while being executed.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go s.heartbeat(ctx, run, func() { cancel(ErrRunLost) })
+
+	var tick <-chan time.Time
+	if run.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / run.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		persons, err := s.personProvider.SelectForReenrich(ctx, run.ReenrichParams, run.LastID, batchSize)
+		if err != nil {
+			return s.finishRun(saveCtx, run, err)
+		}
+
+		if len(persons) == 0 {
+			logger.Info("re-enrichment run done", slog.Int64("processed", run.Processed), slog.Int64("changed", run.Changed))
+
+			return s.finishRun(saveCtx, run, nil)
+		}
+
+		for i := range persons {
+			if tick != nil {
+				select {
+				case <-ctx.Done():
+					return s.finishRun(saveCtx, run, context.Cause(ctx))
+				case <-tick:
+				}
+			}
+
+			if err := s.reenrichPerson(ctx, run, &persons[i]); err != nil {
+				if ctx.Err() != nil {
+					return s.finishRun(saveCtx, run, context.Cause(ctx))
+				}
+
+				logger.Debug("can't re-enrich person", slog.Int64("ID", persons[i].ID), slog.String("[ERROR]", err.Error()))
+				run.Failed++
+			}
+
+			run.Processed++
+			run.LastID = persons[i].ID
+
+			if ctx.Err() != nil {
+				return s.finishRun(saveCtx, run, context.Cause(ctx))
+			}
+
+			err := s.personProvider.SaveReenrichProgress(saveCtx, run)
+			if errors.Is(err, storage.ErrRunNotRunning) {
+				return s.finishRun(saveCtx, run, ErrRunLost)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+}
+
+// finishRun saves final state of run, a run stopped by ctx can be resumed.
+// Nothing is saved for a lost run, it belongs to whoever stopped it.
+func (s *Service) finishRun(ctx context.Context, run *models.ReenrichRun, err error) error {
+	const op = "services.people.finishRun"
+
+	if errors.Is(err, ErrRunLost) {
+		return err
+	}
+
+	run.State = models.RunDone
+	run.Error = ""
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		run.State = models.RunStopped
+		err = nil
+	case err != nil:
+		run.State = models.RunFailed
+		run.Error = err.Error()
+	}
+
+	if err := s.personProvider.UpdateReenrichRun(ctx, run); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return err
+}
+
+// reenrichPerson enriches person again and saves the values which have been enriched.
+func (s *Service) reenrichPerson(ctx context.Context, run *models.ReenrichRun, person *models.Person) error {
+	enriched, err := s.enricher.Process(ctx, models.RawPerson{
+		Name:       person.Name,
+		Surname:    person.Surname,
+		Patronymic: person.Patronymic,
+		Country:    person.CountryHint,
+	})
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	err = s.personProvider.UpdateEnrichment(ctx, person.ID, func(person *models.Person) bool {
+		changed = refill(person, enriched)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(changed) > 0 {
+		run.Changed++
+	}
+
+	for _, attr := range changed {
+		switch attr {
+		case models.AttributeAge:
+			run.AgeChanged++
+		case models.AttributeGender:
+			run.GenderChanged++
+		case models.AttributeNationality:
+			run.NationalityChanged++
+		}
+	}
+
+	return nil
+}
+
+// refill replaces attributes of person by the enriched ones, attributes which haven't
+// been enriched keep their values. It returns attributes whose values have changed.
+func refill(person *models.Person, enriched *models.Person) []string {
+	var changed []string
+
+	if enriched.AgeSource != "" {
+		if person.Age != enriched.Age {
+			changed = append(changed, models.AttributeAge)
+		}
+		person.Age = enriched.Age
+		person.AgeCount = enriched.AgeCount
+		person.AgeSource = enriched.AgeSource
+		person.PendingAttributes = slices.DeleteFunc(person.PendingAttributes, isAttribute(models.AttributeAge))
+	}
+
+	if enriched.GenderSource != "" {
+		if person.Gender != enriched.Gender {
+			changed = append(changed, models.AttributeGender)
+		}
+		person.Gender = enriched.Gender
+		person.GenderProbability = enriched.GenderProbability
+		person.GenderCount = enriched.GenderCount
+		person.GenderSource = enriched.GenderSource
+		person.PendingAttributes = slices.DeleteFunc(person.PendingAttributes, isAttribute(models.AttributeGender))
+	}
+
+	if enriched.NationalitySource != "" {
+		if person.Nationality != enriched.Nationality {
+			changed = append(changed, models.AttributeNationality)
+		}
+		person.Nationality = enriched.Nationality
+		person.NationalityProbability = enriched.NationalityProbability
+		person.NationalityCount = enriched.NationalityCount
+		person.NationalitySource = enriched.NationalitySource
+		person.Countries = enriched.Countries
+		person.PendingAttributes = slices.DeleteFunc(person.PendingAttributes, isAttribute(models.AttributeNationality))
+	}
+
+	person.Calls = enriched.Calls
+	person.EnrichmentStatus = models.EnrichmentComplete
+	if len(person.PendingAttributes) > 0 {
+		person.EnrichmentStatus = models.EnrichmentPending
+	}
+
+	return changed
+}
+
+// isAttribute returns predicate matching attr.
+func isAttribute(attr string) func(string) bool {
+	return func(a string) bool {
+		return a == attr
+	}
+}
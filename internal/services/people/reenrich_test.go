@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/storage"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRunStore an in-memory store of re-enrichment runs, other storage methods aren't implemented.
+type fakeRunStore struct {
+	PersonProvider
+
+	mu   sync.Mutex
+	runs map[int64]models.ReenrichRun
+}
+
+func (f *fakeRunStore) SelectQueuedReenrichRuns(context.Context) ([]models.ReenrichRun, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var runs []models.ReenrichRun
+	for id := int64(1); id <= int64(len(f.runs)); id++ {
+		if run := f.runs[id]; run.State == models.RunQueued {
+			runs = append(runs, run)
+		}
+	}
+
+	return runs, nil
+}
+
+func (f *fakeRunStore) StopStaleReenrichRuns(_ context.Context, staleAfter time.Duration, reason string) ([]int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ids []int64
+	for id, run := range f.runs {
+		if run.State == models.RunRunning && time.Since(run.HeartbeatAt) > staleAfter {
+			run.State, run.Error = models.RunStopped, reason
+			f.runs[id] = run
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func (f *fakeRunStore) HeartbeatReenrichRun(_ context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	run := f.runs[id]
+	if run.State != models.RunRunning {
+		return storage.ErrRunNotRunning
+	}
+	run.HeartbeatAt = time.Now()
+	f.runs[id] = run
+
+	return nil
+}
+
+func (f *fakeRunStore) UpdateReenrichRun(_ context.Context, run *models.ReenrichRun) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	run.HeartbeatAt = time.Now()
+	f.runs[run.ID] = *run
+
+	return nil
+}
+
+func (f *fakeRunStore) SaveReenrichProgress(_ context.Context, run *models.ReenrichRun) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.runs[run.ID].State != models.RunRunning {
+		return storage.ErrRunNotRunning
+	}
+	run.HeartbeatAt = time.Now()
+	f.runs[run.ID] = *run
+
+	return nil
+}
+
+func TestRecoverRuns(t *testing.T) {
+	stale := time.Now().Add(-time.Hour)
+	store := &fakeRunStore{runs: map[int64]models.ReenrichRun{
+		1: {ID: 1, State: models.RunDone},
+		2: {ID: 2, State: models.RunQueued},
+		3: {ID: 3, State: models.RunRunning, LastID: 40, HeartbeatAt: stale},
+		4: {ID: 4, State: models.RunQueued},
+		5: {ID: 5, State: models.RunStopped},
+		6: {ID: 6, State: models.RunRunning, LastID: 70, HeartbeatAt: time.Now()},
+	}}
+
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil, WithRunHeartbeat(time.Minute))
+	s.runs = make(chan *models.ReenrichRun, 1)
+
+	s.recoverRuns(context.Background())
+	s.stopStaleRuns(context.Background())
+
+	if len(s.runs) != 1 {
+		t.Fatalf("queued %d runs, want 1", len(s.runs))
+	}
+	if run := <-s.runs; run.ID != 2 {
+		t.Errorf("queued run %d, want 2", run.ID)
+	}
+
+	want := map[int64]struct {
+		state, err string
+	}{
+		1: {state: models.RunDone},
+		2: {state: models.RunQueued},
+		3: {state: models.RunStopped, err: ErrRunInterrupted.Error()},
+		4: {state: models.RunStopped, err: ErrReenrichBusy.Error()},
+		5: {state: models.RunStopped},
+		6: {state: models.RunRunning},
+	}
+	for id, w := range want {
+		run := store.runs[id]
+		if run.State != w.state || run.Error != w.err {
+			t.Errorf("run %d = %s %q, want %s %q", id, run.State, run.Error, w.state, w.err)
+		}
+	}
+	if store.runs[3].LastID != 40 {
+		t.Errorf("run 3 progress lost, last ID = %d", store.runs[3].LastID)
+	}
+}
+
+// endlessRunStore a fakeRunStore with an endless sequence of persons to re-enrich.
+type endlessRunStore struct {
+	fakeRunStore
+}
+
+func (f *endlessRunStore) SelectForReenrich(_ context.Context, _ models.ReenrichParams, afterID int64, _ int) ([]models.Person, error) {
+	return []models.Person{{ID: afterID + 1, Name: "anna"}}, nil
+}
+
+func (f *endlessRunStore) UpdateEnrichment(_ context.Context, id int64, fill func(*models.Person) bool) error {
+	fill(&models.Person{ID: id, Name: "anna"})
+
+	return nil
+}
+
+func TestRunReenrichLost(t *testing.T) {
+	store := &endlessRunStore{fakeRunStore{runs: map[int64]models.ReenrichRun{1: {ID: 1, State: models.RunQueued}}}}
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, &hintEnricher{}, WithRunHeartbeat(5*time.Millisecond))
+
+	// another executor takes the run over once it has been stopped.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+
+		store.mu.Lock()
+		defer store.mu.Unlock()
+
+		run := store.runs[1]
+		run.State = models.RunStopped
+		store.runs[1] = run
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	run := store.runs[1]
+	if err := s.RunReenrich(ctx, &run, 10); !errors.Is(err, ErrRunLost) {
+		t.Fatalf("RunReenrich() error = %v, want ErrRunLost", err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if state := store.runs[1].State; state != models.RunStopped {
+		t.Errorf("run state = %s, want %s left by whoever stopped it", state, models.RunStopped)
+	}
+}
+
+func TestCheckResumable(t *testing.T) {
+	tests := []struct {
+		state string
+		want  error
+	}{
+		{state: models.RunQueued, want: ErrRunActive},
+		{state: models.RunRunning, want: ErrRunActive},
+		{state: models.RunDone, want: ErrRunFinished},
+		{state: models.RunStopped},
+		{state: models.RunFailed},
+	}
+
+	for _, tt := range tests {
+		if err := CheckResumable(&models.ReenrichRun{State: tt.state}); !errors.Is(err, tt.want) {
+			t.Errorf("CheckResumable(%s) = %v, want %v", tt.state, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/lib/pq"
+	"strings"
+)
+
+// sortColumns expressions of sortable fields, they match the values read by personColumns.
+var sortColumns = map[string]string{
+	"id":                      "id",
+	"name":                    "name",
+	"surname":                 "surname",
+	"patronymic":              "patronymic",
+	"age":                     "COALESCE(age, 0)",
+	"gender":                  "COALESCE(gender, '')",
+	"gender_probability":      "gender_probability",
+	"nationality":             "COALESCE(nationality, '')",
+	"nationality_probability": "nationality_probability",
+	"enriched_at":             "enriched_at",
+}
+
+// likeEscaper escapes LIKE wildcards of user values.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// filter builds WHERE clause of persons query with numbered placeholders, it follows
+// the semantics of models.Params. Column names never come from user input.
+type filter struct {
+	conds []string
+	args  []interface{}
+}
+
+// newFilter returns filter of params.
+func newFilter(params models.Params) *filter {
+	f := &filter{}
+
+	f.text("name", params.Name)
+	f.text("surname", params.Surname)
+	f.text("patronymic", params.Patronymic)
+
+	if params.AgeMin != nil {
+		f.add(`COALESCE(age, 0) >= %s`, *params.AgeMin)
+	}
+
+	if params.AgeMax != nil {
+		f.add(`COALESCE(age, 0) <= %s`, *params.AgeMax)
+	}
+
+	if len(params.Genders) > 0 {
+		f.add(`COALESCE(gender, '') = ANY(%s)`, pq.Array(params.Genders))
+	}
+
+	if len(params.Nationalities) > 0 {
+		f.add(`COALESCE(nationality, '') = ANY(%s)`, pq.Array(params.Nationalities))
+	}
+
+	if params.Country != "" {
+		f.args = append(f.args, params.Country, params.CountryProbability)
+		f.conds = append(f.conds, fmt.Sprintf(`EXISTS (SELECT 1 FROM person_countries pc
+			WHERE pc.person_id = persons.id AND pc.country_id = $%d AND pc.probability >= $%d)`,
+			len(f.args)-1, len(f.args)))
+	}
+
+	return f
+}
+
+// text adds case-insensitive condition of text column.
+func (f *filter) text(column string, tf models.TextFilter) {
+	if tf.Value == "" {
+		return
+	}
+
+	switch tf.Match {
+	case models.MatchPrefix:
+		f.add(`lower(`+column+`) LIKE lower(%s) ESCAPE '\'`, likeEscaper.Replace(tf.Value)+"%")
+	case models.MatchContains:
+		f.add(`lower(`+column+`) LIKE lower(%s) ESCAPE '\'`, "%"+likeEscaper.Replace(tf.Value)+"%")
+	default:
+		f.add(`lower(`+column+`) = lower(%s)`, tf.Value)
+	}
+}
+
+// add adds condition with placeholder of arg in place of %s.
+func (f *filter) add(cond string, arg interface{}) {
+	f.args = append(f.args, arg)
+	f.conds = append(f.conds, fmt.Sprintf(cond, fmt.Sprintf("$%d", len(f.args))))
+}
+
+// where returns WHERE clause or empty string if there are no conditions.
+func (f *filter) where() string {
+	if len(f.conds) == 0 {
+		return ""
+	}
+
+	return ` WHERE ` + strings.Join(f.conds, ` AND `)
+}
+
+// keyset adds condition selecting persons after or before position k of persons sorted by fields.
+// Fields sorted in the same direction are compared as a row to let the index serve the condition.
+func (f *filter) keyset(fields []models.SortField, k *models.Keyset) {
+	placeholders := make([]string, len(k.Values))
+	for i, v := range k.Values {
+		f.args = append(f.args, v)
+		placeholders[i] = fmt.Sprintf("$%d", len(f.args))
+	}
+
+	if uniform(fields) {
+		columns := make([]string, 0, len(fields))
+		for _, field := range fields {
+			columns = append(columns, sortColumns[field.Field])
+		}
+
+		op := ` > `
+		if fields[0].Desc != k.Backward {
+			op = ` < `
+		}
+		f.conds = append(f.conds, `(`+strings.Join(columns, `, `)+`)`+op+`(`+strings.Join(placeholders, `, `)+`)`)
+
+		return
+	}
+
+	ors := make([]string, 0, len(fields))
+
+	for i, field := range fields {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, sortColumns[fields[j].Field]+` = `+placeholders[j])
+		}
+
+		op := ` > `
+		if field.Desc != k.Backward {
+			op = ` < `
+		}
+		ands = append(ands, sortColumns[field.Field]+op+placeholders[i])
+
+		ors = append(ors, `(`+strings.Join(ands, ` AND `)+`)`)
+	}
+
+	f.conds = append(f.conds, `(`+strings.Join(ors, ` OR `)+`)`)
+}
+
+// uniform reports whether all fields are sorted in the same direction.
+func uniform(fields []models.SortField) bool {
+	for _, f := range fields[1:] {
+		if f.Desc != fields[0].Desc {
+			return false
+		}
+	}
+
+	return true
+}
+
+// orderBy returns ORDER BY clause of key fields, the order is reversed if backward.
+func orderBy(fields []models.SortField, backward bool) string {
+	terms := make([]string, 0, len(fields))
+
+	for _, f := range fields {
+		column := sortColumns[f.Field]
+		if f.Desc != backward {
+			column += ` DESC`
+		}
+		terms = append(terms, column)
+	}
+
+	return ` ORDER BY ` + strings.Join(terms, `, `)
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/lib/pq"
+	"reflect"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	age := func(n uint) *uint { return &n }
+
+	tests := []struct {
+		name      string
+		params    models.Params
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{name: "no filters", params: models.Params{}, wantWhere: ""},
+		{
+			name:      "exact name",
+			params:    models.Params{Name: models.TextFilter{Value: "Dmitriy", Match: models.MatchExact}},
+			wantWhere: ` WHERE lower(name) = lower($1)`,
+			wantArgs:  []interface{}{"Dmitriy"},
+		},
+		{
+			name:      "prefix escapes wildcards",
+			params:    models.Params{Surname: models.TextFilter{Value: `u_sh%\`, Match: models.MatchPrefix}},
+			wantWhere: ` WHERE lower(surname) LIKE lower($1) ESCAPE '\'`,
+			wantArgs:  []interface{}{`u\_sh\%\\%`},
+		},
+		{
+			name:      "contains",
+			params:    models.Params{Patronymic: models.TextFilter{Value: "vich", Match: models.MatchContains}},
+			wantWhere: ` WHERE lower(patronymic) LIKE lower($1) ESCAPE '\'`,
+			wantArgs:  []interface{}{"%vich%"},
+		},
+		{
+			name:      "age range",
+			params:    models.Params{AgeMin: age(18), AgeMax: age(30)},
+			wantWhere: ` WHERE COALESCE(age, 0) >= $1 AND COALESCE(age, 0) <= $2`,
+			wantArgs:  []interface{}{uint(18), uint(30)},
+		},
+		{
+			name:      "genders and nationalities",
+			params:    models.Params{Genders: []string{"male"}, Nationalities: []string{"RU", "UA"}},
+			wantWhere: ` WHERE COALESCE(gender, '') = ANY($1) AND COALESCE(nationality, '') = ANY($2)`,
+			wantArgs:  []interface{}{pq.Array([]string{"male"}), pq.Array([]string{"RU", "UA"})},
+		},
+		{
+			name:   "country after name",
+			params: models.Params{Name: models.TextFilter{Value: "anna"}, Country: "UA", CountryProbability: 0.3},
+			wantWhere: ` WHERE lower(name) = lower($1) AND EXISTS (SELECT 1 FROM person_countries pc
+			WHERE pc.person_id = persons.id AND pc.country_id = $2 AND pc.probability >= $3)`,
+			wantArgs: []interface{}{"anna", "UA", 0.3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFilter(tt.params)
+
+			if got := f.where(); got != tt.wantWhere {
+				t.Errorf("where() = %q, want %q", got, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(f.args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", f.args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	tests := []struct {
+		name      string
+		sort      []models.SortField
+		backward  bool
+		wantWhere string
+		wantOrder string
+	}{
+		{
+			name:      "by id",
+			wantWhere: ` WHERE (id) > ($1)`,
+			wantOrder: ` ORDER BY id`,
+		},
+		{
+			name:      "by name",
+			sort:      []models.SortField{{Field: "name"}},
+			wantWhere: ` WHERE (name, id) > ($1, $2)`,
+			wantOrder: ` ORDER BY name, id`,
+		},
+		{
+			name:      "by age descending",
+			sort:      []models.SortField{{Field: "age", Desc: true}},
+			wantWhere: ` WHERE (COALESCE(age, 0), id) < ($1, $2)`,
+			wantOrder: ` ORDER BY COALESCE(age, 0) DESC, id DESC`,
+		},
+		{
+			name:      "by surname backward",
+			sort:      []models.SortField{{Field: "surname"}},
+			backward:  true,
+			wantWhere: ` WHERE (surname, id) < ($1, $2)`,
+			wantOrder: ` ORDER BY surname DESC, id DESC`,
+		},
+		{
+			name:      "mixed directions",
+			sort:      []models.SortField{{Field: "age"}, {Field: "surname", Desc: true}},
+			wantWhere: ` WHERE ((COALESCE(age, 0) > $1) OR (COALESCE(age, 0) = $1 AND surname < $2) OR (COALESCE(age, 0) = $1 AND surname = $2 AND id < $3))`,
+			wantOrder: ` ORDER BY COALESCE(age, 0), surname DESC, id DESC`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := models.Params{Sort: tt.sort}.KeyFields()
+			k := &models.Keyset{Values: make([]interface{}, len(fields)), Backward: tt.backward}
+
+			f := &filter{}
+			f.keyset(fields, k)
+
+			if got := f.where(); got != tt.wantWhere {
+				t.Errorf("where() = %q, want %q", got, tt.wantWhere)
+			}
+			if got := orderBy(fields, tt.backward); got != tt.wantOrder {
+				t.Errorf("orderBy() = %q, want %q", got, tt.wantOrder)
+			}
+		})
+	}
+}
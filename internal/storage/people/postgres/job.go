@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/storage"
+)
+
+// jobColumns columns of jobs table.
+const jobColumns = `id, person_id, state, country, error, created_at, updated_at`
+
+// CreateJob creates not enriched person and a queued job to enrich it.
+func (s *Storage) CreateJob(ctx context.Context, person models.Person, country string) (*models.Job, error) {
+	const op = "storage.postgres.CreateJob"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := insertPerson(ctx, tx, person)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `INSERT INTO jobs (person_id, state, country) VALUES ($1, $2, $3) RETURNING ` + jobColumns
+
+	var job models.Job
+	if err := tx.GetContext(ctx, &job, query, id, models.JobQueued, country); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &job, nil
+}
+
+// SelectJob returns a job by ID.
+func (s *Storage) SelectJob(ctx context.Context, jobID int64) (*models.Job, error) {
+	const op = "storage.postgres.SelectJob"
+
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+
+	var job models.Job
+	if err := s.db.GetContext(ctx, &job, query, jobID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrJobNotFound
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &job, nil
+}
+
+// SelectQueuedJobs returns at most limit oldest queued jobs.
+func (s *Storage) SelectQueuedJobs(ctx context.Context, limit int) ([]models.Job, error) {
+	const op = "storage.postgres.SelectQueuedJobs"
+
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE state = $1 ORDER BY id LIMIT $2`
+
+	jobs := make([]models.Job, 0)
+	if err := s.db.SelectContext(ctx, &jobs, query, models.JobQueued, limit); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return jobs, nil
+}
+
+// RequeueRunningJobs returns running jobs to queued state and reports how many were requeued.
+func (s *Storage) RequeueRunningJobs(ctx context.Context) (int64, error) {
+	const op = "storage.postgres.RequeueRunningJobs"
+
+	query := `UPDATE jobs SET state = $1, updated_at = NOW() WHERE state = $2`
+
+	res, err := s.db.ExecContext(ctx, query, models.JobQueued, models.JobRunning)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// ClaimJob moves queued job to running state, ErrJobNotQueued is returned
+// if the job has been claimed already.
+func (s *Storage) ClaimJob(ctx context.Context, jobID int64) error {
+	const op = "storage.postgres.ClaimJob"
+
+	query := `UPDATE jobs SET state = $1, updated_at = NOW() WHERE id = $2 AND state = $3`
+
+	res, err := s.db.ExecContext(ctx, query, models.JobRunning, jobID, models.JobQueued)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return storage.ErrJobNotQueued
+	}
+
+	return nil
+}
+
+// UpdateJob updates job state and error.
+func (s *Storage) UpdateJob(ctx context.Context, jobID int64, state, errMsg string) error {
+	const op = "storage.postgres.UpdateJob"
+
+	query := `UPDATE jobs SET state = $1, error = $2, updated_at = NOW() WHERE id = $3`
+
+	res, err := s.db.ExecContext(ctx, query, state, errMsg, jobID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return storage.ErrJobNotFound
+	}
+
+	return nil
+}
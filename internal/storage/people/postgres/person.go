@@ -8,9 +8,18 @@ import (
 	"github.com/binaryty/enricher-service/internal/models"
 	"github.com/binaryty/enricher-service/internal/storage"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"slices"
+	"strings"
+	"time"
 )
 
+// personColumns columns of persons table, pending attributes are read as zero values.
+const personColumns = `id, name, surname, patronymic, COALESCE(age, 0) AS age, age_count, age_source,
+	COALESCE(gender, '') AS gender, gender_probability, gender_count, gender_source,
+	COALESCE(nationality, '') AS nationality, nationality_probability, nationality_count, nationality_source,
+	enrichment_status, pending_attributes, enriched_at, country_hint`
+
 // ConfigProvider an interface implements config.
 type ConfigProvider interface {
 	MakePGURL() string
@@ -39,28 +48,146 @@ func New(cfg ConfigProvider) (*Storage, error) {
 func (s *Storage) Create(ctx context.Context, person models.Person) (int64, error) {
 	const op = "storage.postgres.Create"
 
-	query := `INSERT INTO persons (name, surname, patronymic, age, gender, nationality)
-		VALUES(:name, :surname, :patronymic, :age, :gender, :nationality) ON CONFLICT DO NOTHING RETURNING id`
-
-	stmt, err := s.db.PrepareNamedContext(ctx, query)
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %w", op, err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	var id int64
-	err = stmt.GetContext(ctx, &id, person)
+	id, err := insertPerson(ctx, tx, person)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// insertPerson saves person with its nationality distribution and provider calls.
+func insertPerson(ctx context.Context, tx *sqlx.Tx, person models.Person) (int64, error) {
+	id, err := insertPersonRows(ctx, tx, person)
+	if err != nil {
+		return -1, invalidData(err)
+	}
+
+	return id, nil
+}
+
+// invalidData returns storage.ErrInvalidData with the message of err if err is a data exception
+// or an integrity constraint violation which fails however many times it is retried, err otherwise.
+func invalidData(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && (pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23") {
+		return fmt.Errorf("%w: %s", storage.ErrInvalidData, pqErr.Message)
+	}
+
+	return err
+}
+
+// insertPersonRows inserts rows of person.
+func insertPersonRows(ctx context.Context, tx *sqlx.Tx, person models.Person) (int64, error) {
+	query := `INSERT INTO persons (name, surname, patronymic, age, age_count, age_source, gender,
+		gender_probability, gender_count, gender_source, nationality, nationality_probability, nationality_count,
+		nationality_source, enrichment_status, pending_attributes, country_hint)
+		VALUES(:name, :surname, :patronymic, :age, :age_count, :age_source, :gender,
+		:gender_probability, :gender_count, :gender_source, :nationality, :nationality_probability, :nationality_count,
+		:nationality_source, :enrichment_status, :pending_attributes, :country_hint) ON CONFLICT DO NOTHING RETURNING id`
+
+	stmt, err := tx.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return -1, err
+	}
+
+	var id int64
+	if err := stmt.GetContext(ctx, &id, personArgs(person)); err != nil {
+		return -1, err
+	}
+
+	if err := insertCountries(ctx, tx, id, person.Countries); err != nil {
+		return -1, err
+	}
+
+	if err := insertCalls(ctx, tx, id, person.Calls); err != nil {
+		return -1, err
+	}
+
 	return id, nil
 }
 
+// personArgs returns named query arguments of person with pending attributes set to NULL.
+func personArgs(person models.Person) map[string]interface{} {
+	args := map[string]interface{}{
+		"id":                      person.ID,
+		"name":                    person.Name,
+		"surname":                 person.Surname,
+		"patronymic":              person.Patronymic,
+		"age":                     person.Age,
+		"age_count":               person.AgeCount,
+		"age_source":              person.AgeSource,
+		"gender":                  person.Gender,
+		"gender_probability":      person.GenderProbability,
+		"gender_count":            person.GenderCount,
+		"gender_source":           person.GenderSource,
+		"nationality":             person.Nationality,
+		"nationality_probability": person.NationalityProbability,
+		"nationality_count":       person.NationalityCount,
+		"nationality_source":      person.NationalitySource,
+		"enrichment_status":       person.EnrichmentStatus,
+		"pending_attributes":      person.PendingAttributes,
+		"country_hint":            person.CountryHint,
+	}
+
+	if person.EnrichmentStatus == "" {
+		args["enrichment_status"] = models.EnrichmentComplete
+	}
+	if person.PendingAttributes == nil {
+		args["pending_attributes"] = pq.StringArray{}
+	}
+
+	for _, attr := range person.PendingAttributes {
+		args[attr] = nil
+	}
+
+	return args
+}
+
+// insertCountries saves nationality distribution of person.
+func insertCountries(ctx context.Context, tx *sqlx.Tx, personID int64, countries []models.Country) error {
+	query := `INSERT INTO person_countries (person_id, country_id, probability) VALUES ($1, $2, $3)
+		ON CONFLICT (person_id, country_id) DO UPDATE SET probability = EXCLUDED.probability`
+
+	for _, c := range countries {
+		if _, err := tx.ExecContext(ctx, query, personID, c.CountryID, c.Probability); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertCalls saves provider calls made to enrich person.
+func insertCalls(ctx context.Context, tx *sqlx.Tx, personID int64, calls []models.EnrichmentCall) error {
+	query := `INSERT INTO enrichment_log (person_id, provider, attribute, request_url, status, latency_ms,
+		response_body, error, cached, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	for _, c := range calls {
+		if _, err := tx.ExecContext(ctx, query, personID, c.Provider, c.Attribute, c.URL, c.Status, c.LatencyMs,
+			c.Body, c.Error, c.Cached, c.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // SelectByID returns a person by ID.
 func (s *Storage) SelectByID(ctx context.Context, personID int64) (*models.Person, error) {
 	const op = "storage.postgres.SelectByID"
 
-	query := `SELECT id, name, surname, patronymic, age, gender, nationality FROM persons WHERE id=$1`
+	query := `SELECT ` + personColumns + ` FROM persons WHERE id=$1`
 
 	var person models.Person
 	if err := s.db.GetContext(
@@ -75,9 +202,44 @@ func (s *Storage) SelectByID(ctx context.Context, personID int64) (*models.Perso
 
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+
+	countries, err := s.selectCountries(ctx, []int64{person.ID})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	person.Countries = countries[person.ID]
+
 	return &person, nil
 }
 
+// selectCountries returns nationality distributions of persons by their IDs.
+func (s *Storage) selectCountries(ctx context.Context, personIDs []int64) (map[int64][]models.Country, error) {
+	query := `SELECT person_id, country_id, probability FROM person_countries
+		WHERE person_id = ANY($1) ORDER BY person_id, probability DESC`
+
+	rows, err := s.db.QueryxContext(ctx, query, pq.Array(personIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	countries := make(map[int64][]models.Country, len(personIDs))
+
+	for rows.Next() {
+		var (
+			personID int64
+			country  models.Country
+		)
+		if err := rows.Scan(&personID, &country.CountryID, &country.Probability); err != nil {
+			return nil, err
+		}
+
+		countries[personID] = append(countries[personID], country)
+	}
+
+	return countries, rows.Err()
+}
+
 // Update updates person.
 func (s *Storage) Update(ctx context.Context, person *models.Person) error {
 	query := `UPDATE persons 
@@ -104,13 +266,115 @@ WHERE id = $7
 	return err
 }
 
+// editableColumns columns of persons which can be written by UpdateFields.
+var editableColumns = []string{
+	"name", "surname", "patronymic", "age", "gender", "nationality", "enrichment_status", "pending_attributes",
+	"age_count", "age_source", "gender_probability", "gender_count", "gender_source",
+	"nationality_probability", "nationality_count", "nationality_source",
+}
+
+// UpdateFields locks person by ID, passes it to update and writes the fields update returns
+// in the same transaction, the keys of fields are column names. Nationality distribution is
+// deleted once nationality is written. Nothing is written if there are no fields. The person
+// changed by update is returned.
+func (s *Storage) UpdateFields(ctx context.Context, id int64, update func(*models.Person) (map[string]interface{}, error)) (*models.Person, error) {
+	const op = "storage.postgres.UpdateFields"
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var person models.Person
+	if err := tx.GetContext(ctx, &person, `SELECT `+personColumns+` FROM persons WHERE id = $1 FOR UPDATE`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	countries, err := s.selectCountries(ctx, []int64{person.ID})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	person.Countries = countries[person.ID]
+
+	fields, err := update(&person)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(fields) == 0 {
+		return &person, nil
+	}
+
+	sets := make([]string, 0, len(fields))
+	args := make([]interface{}, 0, len(fields)+1)
+
+	for _, column := range editableColumns {
+		value, ok := fields[column]
+		if !ok {
+			continue
+		}
+
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	if len(sets) != len(fields) {
+		return nil, fmt.Errorf("%s: unknown column in %v", op, fields)
+	}
+
+	args = append(args, id)
+	query := fmt.Sprintf(`UPDATE persons SET %s WHERE id = $%d`, strings.Join(sets, ", "), len(args))
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, ok := fields["nationality"]; ok {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM person_countries WHERE person_id = $1`, id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &person, nil
+}
+
 // SelectAll returns slice of persons.
 func (s *Storage) SelectAll(ctx context.Context, params models.Params) ([]models.Person, error) {
 	const op = "storage.postgres.SelectAll"
 
-	query := `SELECT * FROM persons ORDER BY id LIMIT $1 OFFSET $2`
+	fields := params.KeyFields()
+	for _, field := range fields {
+		if _, ok := sortColumns[field.Field]; !ok {
+			return nil, fmt.Errorf("%s: %w: %s", op, storage.ErrInvalidSort, field.Field)
+		}
+	}
+
+	f := newFilter(params)
+
+	backward := false
+	if params.After != nil {
+		if len(params.After.Values) != len(fields) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrInvalidKeyset)
+		}
+
+		f.keyset(fields, params.After)
+		backward = params.After.Backward
+	}
 
-	rows, err := s.db.QueryxContext(ctx, query, params.Limit, params.Offset)
+	query := `SELECT ` + personColumns + ` FROM persons` + f.where()
+	args := append(f.args, params.Limit, params.Offset)
+	query += orderBy(fields, backward) + fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	persons, err := s.selectPersons(ctx, query, args...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, storage.ErrNotFound
@@ -118,22 +382,198 @@ func (s *Storage) SelectAll(ctx context.Context, params models.Params) ([]models
 
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+
+	// persons before keyset are selected in reverse order.
+	if backward {
+		slices.Reverse(persons)
+	}
+
+	return persons, nil
+}
+
+// Count returns number of persons matching filters of params.
+func (s *Storage) Count(ctx context.Context, params models.Params) (int64, error) {
+	const op = "storage.postgres.Count"
+
+	f := newFilter(params)
+
+	var total int64
+	if err := s.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM persons`+f.where(), f.args...); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return total, nil
+}
+
+// SelectPending returns persons with pending enrichment whose next attempt is due, the longest waiting first.
+func (s *Storage) SelectPending(ctx context.Context, limit int) ([]models.Person, error) {
+	const op = "storage.postgres.SelectPending"
+
+	query := `SELECT ` + personColumns + ` FROM persons
+WHERE enrichment_status = $1 AND next_attempt_at <= NOW()
+ORDER BY next_attempt_at, id LIMIT $2`
+
+	persons, err := s.selectPersons(ctx, query, models.EnrichmentPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return persons, nil
+}
+
+// DeferPending records a failed enrichment attempt of pending person by ID, the next attempt
+// is due after backoff doubled by every previous attempt. The person is marked failed once
+// it has made maxAttempts attempts.
+func (s *Storage) DeferPending(ctx context.Context, id int64, backoff time.Duration, maxAttempts int) error {
+	const op = "storage.postgres.DeferPending"
+
+	query := `UPDATE persons
+SET pending_attempts = pending_attempts + 1,
+    next_attempt_at = NOW() + make_interval(secs => $2 * power(2, LEAST(pending_attempts, 16))),
+    enrichment_status = CASE WHEN pending_attempts + 1 >= $3 THEN $4 ELSE enrichment_status END
+WHERE id = $1 AND enrichment_status = $5`
+
+	if _, err := s.db.ExecContext(ctx, query, id, backoff.Seconds(), maxAttempts,
+		models.EnrichmentFailed, models.EnrichmentPending); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// selectPersons returns persons selected by query with their nationality distributions.
+func (s *Storage) selectPersons(ctx context.Context, query string, args ...interface{}) ([]models.Person, error) {
+	rows, err := s.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer func() { _ = rows.Close() }()
 
 	persons := make([]models.Person, 0)
+	ids := make([]int64, 0)
 
 	for rows.Next() {
 		var person models.Person
 		if err := rows.StructScan(&person); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return nil, err
 		}
 
 		persons = append(persons, person)
+		ids = append(ids, person.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	countries, err := s.selectCountries(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range persons {
+		persons[i].Countries = countries[persons[i].ID]
 	}
 
 	return persons, nil
 }
 
+// UpdateEnrichment locks person by ID, fills its enriched attributes by fill and writes them
+// with its nationality distribution and enrichment calls. Attributes patched while the person
+// was being enriched are seen by fill. Nothing is written if fill returns false.
+func (s *Storage) UpdateEnrichment(ctx context.Context, id int64, fill func(*models.Person) bool) error {
+	const op = "storage.postgres.UpdateEnrichment"
+
+	query := `UPDATE persons
+SET age = :age,
+    age_count = :age_count,
+    age_source = :age_source,
+    gender = :gender,
+    gender_probability = :gender_probability,
+    gender_count = :gender_count,
+    gender_source = :gender_source,
+    nationality = :nationality,
+    nationality_probability = :nationality_probability,
+    nationality_count = :nationality_count,
+    nationality_source = :nationality_source,
+    enrichment_status = :enrichment_status,
+    pending_attributes = :pending_attributes,
+    pending_attempts = 0,
+    next_attempt_at = NOW(),
+    enriched_at = NOW()
+WHERE id = :id
+`
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var person models.Person
+	if err := tx.GetContext(ctx, &person, `SELECT `+personColumns+` FROM persons WHERE id = $1 FOR UPDATE`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	countries, err := s.selectCountries(ctx, []int64{person.ID})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	person.Countries = countries[person.ID]
+
+	if !fill(&person) {
+		return nil
+	}
+
+	if _, err := tx.NamedExecContext(ctx, query, personArgs(person)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM person_countries WHERE person_id = $1`, person.ID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertCountries(ctx, tx, person.ID, person.Countries); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertCalls(ctx, tx, person.ID, person.Calls); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SelectEnrichmentLog returns provider calls made to enrich person in order they were made.
+func (s *Storage) SelectEnrichmentLog(ctx context.Context, personID int64) ([]models.EnrichmentCall, error) {
+	const op = "storage.postgres.SelectEnrichmentLog"
+
+	var exists bool
+	if err := s.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM persons WHERE id = $1)`, personID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !exists {
+		return nil, storage.ErrNotFound
+	}
+
+	query := `SELECT id, person_id, provider, attribute, request_url, status, latency_ms, response_body, error,
+		cached, created_at FROM enrichment_log WHERE person_id = $1 ORDER BY created_at, id`
+
+	calls := make([]models.EnrichmentCall, 0)
+	if err := s.db.SelectContext(ctx, &calls, query, personID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return calls, nil
+}
+
 // DeleteByID delete person from storage by ID.
 func (s *Storage) DeleteByID(ctx context.Context, personID int64) error {
 	query := `DELETE FROM persons WHERE id = $1`
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/storage"
+	"github.com/lib/pq"
+	"testing"
+)
+
+func TestInvalidData(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "value too long", err: &pq.Error{Code: "22001", Message: "value too long"}, want: true},
+		{name: "not null violation", err: fmt.Errorf("insert: %w", &pq.Error{Code: "23502"}), want: true},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, want: true},
+		{name: "connection failure", err: &pq.Error{Code: "08006"}},
+		{name: "serialization failure", err: &pq.Error{Code: "40001"}},
+		{name: "not a postgres error", err: sql.ErrConnDone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := invalidData(tt.err)
+			if got := errors.Is(err, storage.ErrInvalidData); got != tt.want {
+				t.Errorf("invalidData() = %v, want invalid data %v", err, tt.want)
+			}
+			if !tt.want && err != tt.err {
+				t.Errorf("invalidData() = %v, want %v unchanged", err, tt.err)
+			}
+		})
+	}
+}
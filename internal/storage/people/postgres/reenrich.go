@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/models"
+	"github.com/binaryty/enricher-service/internal/storage"
+	"time"
+)
+
+// runColumns columns of reenrich_runs table.
+const runColumns = `id, state, enriched_before, provider, max_probability, rate, last_id, processed, failed,
+	changed, age_changed, gender_changed, nationality_changed, error, created_at, updated_at, heartbeat_at`
+
+// CreateReenrichRun creates a queued re-enrichment run.
+func (s *Storage) CreateReenrichRun(ctx context.Context, params models.ReenrichParams) (*models.ReenrichRun, error) {
+	const op = "storage.postgres.CreateReenrichRun"
+
+	query := `INSERT INTO reenrich_runs (state, enriched_before, provider, max_probability, rate)
+		VALUES ($1, $2, $3, $4, $5) RETURNING ` + runColumns
+
+	var run models.ReenrichRun
+	if err := s.db.GetContext(ctx, &run, query, models.RunQueued, params.EnrichedBefore, params.Provider,
+		params.MaxProbability, params.Rate); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &run, nil
+}
+
+// SelectReenrichRun returns a re-enrichment run by ID.
+func (s *Storage) SelectReenrichRun(ctx context.Context, runID int64) (*models.ReenrichRun, error) {
+	const op = "storage.postgres.SelectReenrichRun"
+
+	query := `SELECT ` + runColumns + ` FROM reenrich_runs WHERE id = $1`
+
+	var run models.ReenrichRun
+	if err := s.db.GetContext(ctx, &run, query, runID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrRunNotFound
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &run, nil
+}
+
+// SelectQueuedReenrichRuns returns queued re-enrichment runs.
+func (s *Storage) SelectQueuedReenrichRuns(ctx context.Context) ([]models.ReenrichRun, error) {
+	const op = "storage.postgres.SelectQueuedReenrichRuns"
+
+	query := `SELECT ` + runColumns + ` FROM reenrich_runs WHERE state = $1 ORDER BY id`
+
+	runs := make([]models.ReenrichRun, 0)
+	if err := s.db.SelectContext(ctx, &runs, query, models.RunQueued); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return runs, nil
+}
+
+// HeartbeatReenrichRun records that running re-enrichment run by ID is still executed,
+// storage.ErrRunNotRunning is returned if the run isn't running anymore.
+func (s *Storage) HeartbeatReenrichRun(ctx context.Context, runID int64) error {
+	const op = "storage.postgres.HeartbeatReenrichRun"
+
+	res, err := s.db.ExecContext(ctx, `UPDATE reenrich_runs SET heartbeat_at = NOW() WHERE id = $1 AND state = $2`,
+		runID, models.RunRunning)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRunNotRunning)
+	}
+
+	return nil
+}
+
+// StopStaleReenrichRuns stops running re-enrichment runs without heartbeat for staleAfter
+// with error reason and returns their IDs.
+func (s *Storage) StopStaleReenrichRuns(ctx context.Context, staleAfter time.Duration, reason string) ([]int64, error) {
+	const op = "storage.postgres.StopStaleReenrichRuns"
+
+	query := `UPDATE reenrich_runs SET state = $1, error = $2, updated_at = NOW()
+WHERE state = $3 AND heartbeat_at < NOW() - make_interval(secs => $4)
+RETURNING id`
+
+	ids := make([]int64, 0)
+	if err := s.db.SelectContext(ctx, &ids, query, models.RunStopped, reason, models.RunRunning, staleAfter.Seconds()); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// UpdateReenrichRun saves state and progress of a re-enrichment run.
+func (s *Storage) UpdateReenrichRun(ctx context.Context, run *models.ReenrichRun) error {
+	const op = "storage.postgres.UpdateReenrichRun"
+
+	query := `UPDATE reenrich_runs
+SET state = :state,
+    last_id = :last_id,
+    processed = :processed,
+    failed = :failed,
+    changed = :changed,
+    age_changed = :age_changed,
+    gender_changed = :gender_changed,
+    nationality_changed = :nationality_changed,
+    error = :error,
+    updated_at = NOW(),
+    heartbeat_at = NOW()
+WHERE id = :id
+`
+
+	res, err := s.db.NamedExecContext(ctx, query, run)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return storage.ErrRunNotFound
+	}
+
+	return nil
+}
+
+// SaveReenrichProgress saves progress of running re-enrichment run,
+// storage.ErrRunNotRunning is returned if the run isn't running anymore.
+func (s *Storage) SaveReenrichProgress(ctx context.Context, run *models.ReenrichRun) error {
+	const op = "storage.postgres.SaveReenrichProgress"
+
+	query := `UPDATE reenrich_runs
+SET last_id = :last_id,
+    processed = :processed,
+    failed = :failed,
+    changed = :changed,
+    age_changed = :age_changed,
+    gender_changed = :gender_changed,
+    nationality_changed = :nationality_changed,
+    updated_at = NOW(),
+    heartbeat_at = NOW()
+WHERE id = :id AND state = '` + models.RunRunning + `'
+`
+
+	res, err := s.db.NamedExecContext(ctx, query, run)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRunNotRunning)
+	}
+
+	return nil
+}
+
+// SelectForReenrich returns enriched persons matching params with ID greater than afterID.
+func (s *Storage) SelectForReenrich(ctx context.Context, params models.ReenrichParams, afterID int64, limit int) ([]models.Person, error) {
+	const op = "storage.postgres.SelectForReenrich"
+
+	args := []interface{}{afterID, models.EnrichmentQueued}
+	query := `SELECT ` + personColumns + ` FROM persons WHERE id > $1 AND enrichment_status <> $2`
+
+	if params.EnrichedBefore != nil {
+		args = append(args, *params.EnrichedBefore)
+		query += fmt.Sprintf(` AND enriched_at < $%d`, len(args))
+	}
+
+	if params.Provider != "" {
+		args = append(args, params.Provider)
+		query += fmt.Sprintf(` AND $%d = ANY(string_to_array(age_source || ',' || gender_source || ',' ||
+			nationality_source, ','))`, len(args))
+	}
+
+	if params.MaxProbability > 0 {
+		args = append(args, params.MaxProbability)
+		query += fmt.Sprintf(` AND (gender_probability < $%[1]d OR nationality_probability < $%[1]d)`, len(args))
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY id LIMIT $%d`, len(args))
+
+	persons, err := s.selectPersons(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return persons, nil
+}
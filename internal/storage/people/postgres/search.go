@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/binaryty/enricher-service/internal/models"
+)
+
+// fullName expression of full name of person, it matches the trigram index of persons.
+const fullName = `(name || ' ' || surname || ' ' || COALESCE(patronymic, ''))`
+
+// Search returns at most limit persons whose names are similar to q, the most similar first.
+// A person matches if any of its names is similar to q or q is similar to a part of its full name.
+func (s *Storage) Search(ctx context.Context, q string, limit int) ([]models.SearchResult, error) {
+	const op = "storage.postgres.Search"
+
+	query := `SELECT ` + personColumns + `,
+	GREATEST(similarity(name, $1), similarity(surname, $1), similarity(COALESCE(patronymic, ''), $1),
+		word_similarity($1, ` + fullName + `)) AS score
+FROM persons
+WHERE name % $1 OR surname % $1 OR COALESCE(patronymic, '') % $1 OR $1 <% ` + fullName + `
+ORDER BY score DESC, id
+LIMIT $2`
+
+	rows, err := s.db.QueryxContext(ctx, query, q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	results := make([]models.SearchResult, 0)
+	ids := make([]int64, 0)
+
+	for rows.Next() {
+		var res models.SearchResult
+		if err := rows.StructScan(&res); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		results = append(results, res)
+		ids = append(ids, res.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	countries, err := s.selectCountries(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range results {
+		results[i].Countries = countries[results[i].ID]
+	}
+
+	return results, nil
+}
@@ -3,5 +3,12 @@ package storage
 import "errors"
 
 var (
-	ErrNotFound = errors.New("person not found")
+	ErrNotFound      = errors.New("person not found")
+	ErrJobNotFound   = errors.New("job not found")
+	ErrJobNotQueued  = errors.New("job isn't queued")
+	ErrRunNotFound   = errors.New("re-enrichment run not found")
+	ErrRunNotRunning = errors.New("re-enrichment run isn't running")
+	ErrInvalidSort   = errors.New("invalid sort field")
+	ErrInvalidKeyset = errors.New("keyset doesn't match sort fields")
+	ErrInvalidData   = errors.New("data violates storage constraints")
 )
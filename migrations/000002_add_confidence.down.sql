@@ -0,0 +1,6 @@
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS age_count,
+    DROP COLUMN IF EXISTS gender_probability,
+    DROP COLUMN IF EXISTS gender_count,
+    DROP COLUMN IF EXISTS nationality_probability,
+    DROP COLUMN IF EXISTS nationality_count;
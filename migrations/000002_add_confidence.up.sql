@@ -0,0 +1,6 @@
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS age_count BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS gender_probability DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS gender_count BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS nationality_probability DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS nationality_count BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS person_countries (
+                         person_id BIGINT NOT NULL REFERENCES persons (id) ON DELETE CASCADE,
+                         country_id VARCHAR(2) NOT NULL,
+                         probability DOUBLE PRECISION NOT NULL,
+                         PRIMARY KEY (person_id, country_id)
+);
+
+CREATE INDEX ON "person_countries" ("country_id", "probability");
@@ -0,0 +1,8 @@
+UPDATE persons SET age = COALESCE(age, 0), gender = COALESCE(gender, ''), nationality = COALESCE(nationality, '');
+
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS pending_attributes,
+    DROP COLUMN IF EXISTS enrichment_status,
+    ALTER COLUMN age SET NOT NULL,
+    ALTER COLUMN gender SET NOT NULL,
+    ALTER COLUMN nationality SET NOT NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE persons
+    ALTER COLUMN age DROP NOT NULL,
+    ALTER COLUMN gender DROP NOT NULL,
+    ALTER COLUMN nationality DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS enrichment_status VARCHAR(16) NOT NULL DEFAULT 'complete',
+    ADD COLUMN IF NOT EXISTS pending_attributes TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX ON "persons" ("id") WHERE enrichment_status = 'pending';
@@ -0,0 +1,4 @@
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS nationality_source,
+    DROP COLUMN IF EXISTS gender_source,
+    DROP COLUMN IF EXISTS age_source;
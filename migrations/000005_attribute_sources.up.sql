@@ -0,0 +1,4 @@
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS age_source TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS gender_source TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS nationality_source TEXT NOT NULL DEFAULT '';
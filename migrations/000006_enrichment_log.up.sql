@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS enrichment_log (
+                         id BIGSERIAL PRIMARY KEY,
+                         person_id BIGINT NOT NULL REFERENCES persons (id) ON DELETE CASCADE,
+                         provider VARCHAR(64) NOT NULL,
+                         attribute VARCHAR(16) NOT NULL,
+                         request_url TEXT NOT NULL,
+                         status INT NOT NULL,
+                         latency_ms BIGINT NOT NULL,
+                         response_body TEXT NOT NULL DEFAULT '',
+                         error TEXT NOT NULL DEFAULT '',
+                         cached BOOLEAN NOT NULL DEFAULT FALSE,
+                         created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON "enrichment_log" ("person_id", "created_at");
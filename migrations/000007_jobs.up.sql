@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS jobs (
+                         id BIGSERIAL PRIMARY KEY,
+                         person_id BIGINT NOT NULL REFERENCES persons (id) ON DELETE CASCADE,
+                         state VARCHAR(16) NOT NULL DEFAULT 'queued',
+                         country VARCHAR(2) NOT NULL DEFAULT '',
+                         error TEXT NOT NULL DEFAULT '',
+                         created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                         updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON "jobs" ("id") WHERE state IN ('queued', 'running');
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS reenrich_runs;
+
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS enriched_at;
@@ -0,0 +1,23 @@
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS enriched_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX ON "persons" ("enriched_at");
+
+CREATE TABLE IF NOT EXISTS reenrich_runs (
+                         id BIGSERIAL PRIMARY KEY,
+                         state VARCHAR(16) NOT NULL DEFAULT 'queued',
+                         enriched_before TIMESTAMPTZ,
+                         provider VARCHAR(64) NOT NULL DEFAULT '',
+                         max_probability DOUBLE PRECISION NOT NULL DEFAULT 0,
+                         rate DOUBLE PRECISION NOT NULL DEFAULT 0,
+                         last_id BIGINT NOT NULL DEFAULT 0,
+                         processed BIGINT NOT NULL DEFAULT 0,
+                         failed BIGINT NOT NULL DEFAULT 0,
+                         changed BIGINT NOT NULL DEFAULT 0,
+                         age_changed BIGINT NOT NULL DEFAULT 0,
+                         gender_changed BIGINT NOT NULL DEFAULT 0,
+                         nationality_changed BIGINT NOT NULL DEFAULT 0,
+                         error TEXT NOT NULL DEFAULT '',
+                         created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                         updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS persons_name_id_idx;
+DROP INDEX IF EXISTS persons_surname_id_idx;
+DROP INDEX IF EXISTS persons_age_id_idx;
+DROP INDEX IF EXISTS persons_enriched_at_id_idx;
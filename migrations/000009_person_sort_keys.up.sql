@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS persons_name_id_idx ON "persons" ("name", "id");
+CREATE INDEX IF NOT EXISTS persons_surname_id_idx ON "persons" ("surname", "id");
+CREATE INDEX IF NOT EXISTS persons_age_id_idx ON "persons" (COALESCE("age", 0), "id");
+CREATE INDEX IF NOT EXISTS persons_enriched_at_id_idx ON "persons" ("enriched_at", "id");
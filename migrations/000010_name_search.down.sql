@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS persons_name_trgm_idx;
+DROP INDEX IF EXISTS persons_surname_trgm_idx;
+DROP INDEX IF EXISTS persons_patronymic_trgm_idx;
+DROP INDEX IF EXISTS persons_full_name_trgm_idx;
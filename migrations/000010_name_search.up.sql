@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS persons_name_trgm_idx ON "persons" USING GIN ("name" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS persons_surname_trgm_idx ON "persons" USING GIN ("surname" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS persons_patronymic_trgm_idx ON "persons" USING GIN (COALESCE("patronymic", '') gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS persons_full_name_trgm_idx ON "persons"
+    USING GIN (("name" || ' ' || "surname" || ' ' || COALESCE("patronymic", '')) gin_trgm_ops);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS persons_next_attempt_idx;
+
+UPDATE persons SET enrichment_status = 'pending' WHERE enrichment_status = 'failed';
+
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS pending_attempts,
+    DROP COLUMN IF EXISTS next_attempt_at;
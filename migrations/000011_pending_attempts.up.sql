@@ -0,0 +1,5 @@
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS pending_attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS persons_next_attempt_idx ON "persons" ("next_attempt_at", "id") WHERE enrichment_status = 'pending';
@@ -0,0 +1,2 @@
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS country_hint;
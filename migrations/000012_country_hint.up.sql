@@ -0,0 +1,4 @@
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS country_hint VARCHAR(2) NOT NULL DEFAULT '';
+
+UPDATE persons p SET country_hint = j.country FROM jobs j WHERE j.person_id = p.id AND j.country <> '';
@@ -0,0 +1,2 @@
+ALTER TABLE reenrich_runs
+    DROP COLUMN IF EXISTS heartbeat_at;
@@ -0,0 +1,2 @@
+ALTER TABLE reenrich_runs
+    ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW();